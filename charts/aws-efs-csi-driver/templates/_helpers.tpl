@@ -54,3 +54,14 @@ Create a string out of the map for controller tags flag
 {{- end -}}
 {{- join " " $tags -}}
 {{- end -}}
+
+{{/*
+Create a string out of the map for the feature gates flag
+*/}}
+{{- define "aws-efs-csi-driver.featureGates" -}}
+{{- $gates := list -}}
+{{ range $key, $val := . }}
+{{- $gates = print $key "=" $val | append $gates -}}
+{{- end -}}
+{{- join "," $gates -}}
+{{- end -}}
@@ -20,33 +20,31 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"k8s.io/klog/v2"
 
+	cliflag "k8s.io/component-base/cli/flag"
+
 	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver"
 )
 
-// etcAmazonEfs is the non-negotiable directory that the mount.efs will use for config files. We will create a symlink here.
-const etcAmazonEfs = "/etc/amazon/efs"
-
 func main() {
 	var (
-		endpoint                 = flag.String("endpoint", "unix://tmp/csi.sock", "CSI Endpoint")
-		version                  = flag.Bool("version", false, "Print the version and exit")
-		efsUtilsCfgDirPath       = flag.String("efs-utils-config-dir-path", "/var/amazon/efs", "The preferred path for the efs-utils config directory. efs-utils-config-legacy-dir-path will be used if it is not empty, otherwise efs-utils-config-dir-path will be used.")
-		efsUtilsCfgLegacyDirPath = flag.String("efs-utils-config-legacy-dir-path", "/etc/amazon/efs-legacy", "The path to the legacy efs-utils config directory mounted from the host path /etc/amazon/efs")
-		efsUtilsStaticFilesPath  = flag.String("efs-utils-static-files-path", "/etc/amazon/efs-static-files/", "The path to efs-utils static files directory")
-		volMetricsOptIn          = flag.Bool("vol-metrics-opt-in", false, "Opt in to emit volume metrics")
-		volMetricsRefreshPeriod  = flag.Float64("vol-metrics-refresh-period", 240, "Refresh period for volume metrics in minutes")
-		volMetricsFsRateLimit    = flag.Int("vol-metrics-fs-rate-limit", 5, "Volume metrics routines rate limiter per file system")
-		deleteAccessPointRootDir = flag.Bool("delete-access-point-root-dir", false,
-			"Opt in to delete access point root directory by DeleteVolume. By default, DeleteVolume will delete the access point behind Persistent Volume and deleting access point will not delete the access point root directory or its contents.")
-		tags = flag.String("tags", "", "Space separated key:value pairs which will be added as tags for EFS resources. For example, 'environment:prod region:us-east-1'")
+		version    = flag.Bool("version", false, "Print the version and exit")
+		configFile = flag.String("config", "", "Path of the YAML or JSON driver config. The flags that are set override it.")
+		opts       = driver.DefaultDriverOptions()
 	)
+	addFlags(flag.CommandLine, opts)
 	klog.InitFlags(nil)
 	flag.Parse()
 
+	// The version is printed like before the options existed, without loading or validating them. It reports the
+	// feature gates set by flag.
 	if *version {
+		if err := driver.SetFeatureGates(opts.FeatureGates); err != nil {
+			klog.Warning(err)
+		}
 		info, err := driver.GetVersionJSON()
 		if err != nil {
 			klog.Fatalln(err)
@@ -55,13 +53,91 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *configFile != "" {
+		var err error
+		opts, err = loadOptions(*configFile)
+		if err != nil {
+			klog.Fatalln(err)
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		klog.Fatalf("Invalid driver options:\n%v", err)
+	}
+	if err := driver.SetFeatureGates(opts.FeatureGates); err != nil {
+		klog.Fatalln(err)
+	}
+
 	// chose which configuration directory we will use and create a symlink to it
-	err := driver.InitConfigDir(*efsUtilsCfgLegacyDirPath, *efsUtilsCfgDirPath, etcAmazonEfs)
+	err := driver.InitConfigDir(opts.EfsUtils.LegacyConfigDirPath, opts.EfsUtils.ConfigDirPath, opts.EfsUtils.ConfigPath)
 	if err != nil {
 		klog.Fatalln(err)
 	}
-	drv := driver.NewDriver(*endpoint, etcAmazonEfs, *efsUtilsStaticFilesPath, *tags, *volMetricsOptIn, *volMetricsRefreshPeriod, *volMetricsFsRateLimit, *deleteAccessPointRootDir)
+	drv := driver.NewDriver(opts)
 	if err := drv.Run(); err != nil {
 		klog.Fatalln(err)
 	}
 }
+
+// loadOptions reads the driver config file and overrides it with the flags set on the command line.
+func loadOptions(configFile string) (*driver.DriverOptions, error) {
+	opts, err := driver.LoadDriverOptions(configFile)
+	if err != nil {
+		return nil, err
+	}
+	overrides := flag.NewFlagSet("overrides", flag.ContinueOnError)
+	addFlags(overrides, opts)
+	flag.Visit(func(f *flag.Flag) {
+		if overrides.Lookup(f.Name) != nil && err == nil {
+			err = overrides.Set(f.Name, f.Value.String())
+		}
+	})
+	return opts, err
+}
+
+// addFlags adds the flags setting opts to fs. Their defaults are the values of opts.
+func addFlags(fs *flag.FlagSet, opts *driver.DriverOptions) {
+	fs.StringVar(&opts.Endpoint, "endpoint", opts.Endpoint, "CSI Endpoint")
+	fs.StringVar(&opts.Mode, "mode", opts.Mode, "Services to run: 'controller' in the controller Deployment, 'node' in the node DaemonSet, or 'all'.")
+	fs.Var(cliflag.NewMapStringBool(&opts.FeatureGates), "feature-gates", "A set of key=value pairs enabling or disabling the features of the driver, for example 'VolumeMetrics=true,DeleteAccessPointRootDir=false'. Known features: "+strings.Join(driver.KnownFeatures(), ", ")+".")
+	fs.StringVar(&opts.EfsUtils.ConfigDirPath, "efs-utils-config-dir-path", opts.EfsUtils.ConfigDirPath, "The preferred path for the efs-utils config directory. efs-utils-config-legacy-dir-path will be used if it is not empty, otherwise efs-utils-config-dir-path will be used.")
+	fs.StringVar(&opts.EfsUtils.LegacyConfigDirPath, "efs-utils-config-legacy-dir-path", opts.EfsUtils.LegacyConfigDirPath, "The path to the legacy efs-utils config directory mounted from the host path /etc/amazon/efs")
+	fs.StringVar(&opts.EfsUtils.StaticFilesPath, "efs-utils-static-files-path", opts.EfsUtils.StaticFilesPath, "The path to efs-utils static files directory")
+	fs.StringVar(&opts.EfsUtils.SettingsFile, "efs-utils-settings-file", opts.EfsUtils.SettingsFile, "Path of the YAML file overriding the settings of the efs-utils config, by section and key. The defaults of the driver are used if empty.")
+	fs.BoolVar(&opts.Node.VolMetricsOptIn, "vol-metrics-opt-in", opts.Node.VolMetricsOptIn, "Opt in to emit volume metrics")
+	fs.Float64Var(&opts.Node.VolMetricsRefreshPeriod, "vol-metrics-refresh-period", opts.Node.VolMetricsRefreshPeriod, "Refresh period for volume metrics in minutes")
+	fs.IntVar(&opts.Node.VolMetricsFsRateLimit, "vol-metrics-fs-rate-limit", opts.Node.VolMetricsFsRateLimit, "Volume metrics routines rate limiter per file system")
+	fs.IntVar(&opts.Node.VolMetricsWalkConcurrency, "vol-metrics-walk-concurrency", opts.Node.VolMetricsWalkConcurrency, "Number of directories of a volume read at once to compute its usage")
+	fs.StringVar(&opts.Node.VolumeStateFile, "volume-state-file", opts.Node.VolumeStateFile, "Path of the file persisting the volumes published on the node across restarts of the node plugin, e.g. in the plugin directory. Only tracked in memory if empty.")
+	fs.StringVar(&opts.Node.KubeletDir, "kubelet-dir", opts.Node.KubeletDir, "Root directory of kubelet, under which the volumes published on the node are found again when volume-state-file is not set or does not exist yet.")
+	fs.DurationVar(&opts.Node.MountCheckInterval.Duration, "mount-check-interval", opts.Node.MountCheckInterval.Duration, "Interval at which the node plugin stats the volumes it mounted, to report the stale and hung ones through the volume condition. Disabled if 0.")
+	fs.DurationVar(&opts.Node.MountCheckTimeout.Duration, "mount-check-timeout", opts.Node.MountCheckTimeout.Duration, "How long the stat of a mount may take before the mount is reported as hung.")
+	fs.BoolVar(&opts.Node.RemountStaleVolumes, "remount-stale-volumes", opts.Node.RemountStaleVolumes, "Lazily unmount the stale and hung mounts found by mount-check-interval, and mount their volume again.")
+	fs.DurationVar(&opts.Node.MountTimeout.Duration, "mount-timeout", opts.Node.MountTimeout.Duration, "How long mounting a volume may take before NodeStageVolume or NodePublishVolume fail with DeadlineExceeded. Unlimited if 0.")
+	fs.DurationVar(&opts.Node.UnmountTimeout.Duration, "unmount-timeout", opts.Node.UnmountTimeout.Duration, "How long unmounting a volume may take before it is forced, then made lazy. Unlimited if 0, in which case unmounts are neither forced nor made lazy.")
+	fs.StringVar(&opts.Node.MountPolicyFile, "mount-policy-file", opts.Node.MountPolicyFile, "Path of the YAML policy restricting the mount options and volume attributes of the volumes mounted by the node plugin. No restriction if empty.")
+	fs.BoolVar(&opts.Controller.DeleteAccessPointRootDir, "delete-access-point-root-dir", opts.Controller.DeleteAccessPointRootDir,
+		"Opt in to delete access point root directory by DeleteVolume. By default, DeleteVolume will delete the access point behind Persistent Volume and deleting access point will not delete the access point root directory or its contents.")
+	fs.StringVar(&opts.Controller.Tags, "tags", opts.Controller.Tags, "Space separated key:value pairs which will be added as tags for EFS resources. For example, 'environment:prod region:us-east-1'")
+	fs.StringVar(&opts.Controller.ClusterId, "cluster-id", opts.Controller.ClusterId, "Identifier of the cluster, unique among the clusters provisioning on the same file systems. Used by the reuseAccessPointIdentity clusterNamespacedName StorageClass parameter.")
+	fs.StringVar(&opts.Metrics.HealthAddress, "health-address", opts.Metrics.HealthAddress, "Address to serve the /healthz and /readyz endpoints on, for example ':9910'. The endpoints are disabled if empty.")
+	fs.BoolVar(&opts.Controller.EfsAPIHealthCheck, "efs-api-health-check", opts.Controller.EfsAPIHealthCheck, "Include EFS API access in the readiness checks. Intended for the controller, which needs working EFS API credentials.")
+	fs.StringVar(&opts.Metrics.Address, "metrics-address", opts.Metrics.Address, "Address to serve the /metrics endpoint on, for example ':9910'. May be the same as health-address. The endpoint is disabled if empty.")
+
+	fs.StringVar(&opts.Controller.LeaderElectionNamespace, "leader-election-namespace", opts.Controller.LeaderElectionNamespace, "Namespace of the Lease the controller replicas elect the one running the reconcilers with.")
+	fs.DurationVar(&opts.Controller.OrphanedAccessPointReconcileInterval.Duration, "orphaned-access-point-reconcile-interval", opts.Controller.OrphanedAccessPointReconcileInterval.Duration,
+		"Interval at which the controller looks for access points tagged by the driver that no PersistentVolume uses, and reports them through metrics and Events. Disabled if 0.")
+	fs.DurationVar(&opts.Controller.OrphanedAccessPointGracePeriod.Duration, "orphaned-access-point-grace-period", opts.Controller.OrphanedAccessPointGracePeriod.Duration, "How long an access point must be orphaned before it is deleted, if delete-orphaned-access-points is set.")
+	fs.BoolVar(&opts.Controller.DeleteOrphanedAccessPoints, "delete-orphaned-access-points", opts.Controller.DeleteOrphanedAccessPoints,
+		"Opt in to delete orphaned access points after the grace period. Only enable it if the file systems are not shared with other clusters, whose access points look orphaned from this cluster.")
+	fs.DurationVar(&opts.Controller.OrphanedRootDirScanInterval.Duration, "orphaned-root-dir-scan-interval", opts.Controller.OrphanedRootDirScanInterval.Duration,
+		"Interval at which the controller mounts the file systems of its StorageClasses and looks for directories under their basePath that are not used by any access point. Disabled if 0.")
+	fs.BoolVar(&opts.Controller.OrphanedRootDirScanDryRun, "orphaned-root-dir-scan-dry-run", opts.Controller.OrphanedRootDirScanDryRun, "Only report orphaned root directories instead of deleting them.")
+	fs.DurationVar(&opts.Controller.OrphanedRootDirGracePeriod.Duration, "orphaned-root-dir-grace-period", opts.Controller.OrphanedRootDirGracePeriod.Duration, "How long a root directory must be orphaned before it is deleted, if orphaned-root-dir-scan-dry-run is false.")
+	fs.StringVar(&opts.Controller.ForeignAccessPointDeletion, "foreign-access-point-deletion", opts.Controller.ForeignAccessPointDeletion,
+		"What DeleteVolume does with access points tagged as owned by another cluster than cluster-id: 'detach' deletes the volume and keeps the access point, 'refuse' fails until ownership is taken.")
+	fs.StringVar(&opts.Controller.ProtectedAccessPointDeletion, "protected-access-point-deletion", opts.Controller.ProtectedAccessPointDeletion,
+		"What DeleteVolume does with access points tagged for deletion protection: 'refuse' fails, 'detach' deletes the volume and keeps the access point.")
+	fs.StringVar(&opts.Controller.ProvisioningPolicyFile, "provisioning-policy-file", opts.Controller.ProvisioningPolicyFile, "Path of the YAML policy restricting the file systems, basePaths and uid/gid ranges each namespace may provision volumes with. No restriction if empty.")
+	fs.StringVar(&opts.Controller.RoleMappingFile, "role-mapping-file", opts.Controller.RoleMappingFile, "Path of the YAML mapping of PVC namespaces and file system IDs to the IAM roles assumed to provision and delete their volumes, instead of the awsRoleArn of the StorageClass secrets.")
+	fs.BoolVar(&opts.Controller.ArchiveDeletedDirectories, "archive-deleted-directories", opts.Controller.ArchiveDeletedDirectories, "Rename the directories of deleted efs-dir volumes to .archived-<name>-<timestamp> instead of deleting them.")
+}
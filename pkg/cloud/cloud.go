@@ -48,6 +48,8 @@ var (
 	ErrNotFound      = errors.New("Resource was not found")
 	ErrAlreadyExists = errors.New("Resource already exists")
 	ErrAccessDenied  = errors.New("Access denied")
+
+	ErrAccessPointLimitExceeded = errors.New("Access point limit exceeded")
 )
 
 type FileSystem struct {
@@ -62,6 +64,7 @@ type AccessPoint struct {
 	// EFS does not consider capacity while provisioning new file systems or access points
 	CapacityGiB int64
 	PosixUser   *PosixUser
+	Tags        map[string]string
 }
 
 type PosixUser struct {
@@ -96,6 +99,8 @@ type Efs interface {
 	DescribeAccessPoints(context.Context, *efs.DescribeAccessPointsInput, ...func(*efs.Options)) (*efs.DescribeAccessPointsOutput, error)
 	DescribeFileSystems(context.Context, *efs.DescribeFileSystemsInput, ...func(*efs.Options)) (*efs.DescribeFileSystemsOutput, error)
 	DescribeMountTargets(context.Context, *efs.DescribeMountTargetsInput, ...func(*efs.Options)) (*efs.DescribeMountTargetsOutput, error)
+	TagResource(context.Context, *efs.TagResourceInput, ...func(*efs.Options)) (*efs.TagResourceOutput, error)
+	UntagResource(context.Context, *efs.UntagResourceInput, ...func(*efs.Options)) (*efs.UntagResourceOutput, error)
 }
 
 type Cloud interface {
@@ -107,6 +112,9 @@ type Cloud interface {
 	ListAccessPoints(ctx context.Context, fileSystemId string) (accessPoints []*AccessPoint, err error)
 	DescribeFileSystem(ctx context.Context, fileSystemId string) (fs *FileSystem, err error)
 	DescribeMountTargets(ctx context.Context, fileSystemId, az string) (fs *MountTarget, err error)
+	TagAccessPoint(ctx context.Context, accessPointId string, tags map[string]string) (err error)
+	UntagAccessPoint(ctx context.Context, accessPointId string, tagKeys []string) (err error)
+	ValidateCredentials(ctx context.Context) (err error)
 }
 
 type cloud struct {
@@ -198,6 +206,9 @@ func (c *cloud) CreateAccessPoint(ctx context.Context, clientToken string, acces
 		if isAccessDenied(err) {
 			return nil, ErrAccessDenied
 		}
+		if isAccessPointLimitExceeded(err) {
+			return nil, ErrAccessPointLimitExceeded
+		}
 		return nil, fmt.Errorf("Failed to create access point: %v", err)
 	}
 	klog.V(5).Infof("Create AP response : %+v", res)
@@ -249,6 +260,7 @@ func (c *cloud) DescribeAccessPoint(ctx context.Context, accessPointId string) (
 		AccessPointId:      *accessPoints[0].AccessPointId,
 		FileSystemId:       *accessPoints[0].FileSystemId,
 		AccessPointRootDir: *accessPoints[0].RootDirectory.Path,
+		Tags:               parseTagsFromEfs(accessPoints[0].Tags),
 	}, nil
 }
 
@@ -273,11 +285,16 @@ func (c *cloud) FindAccessPointByClientToken(ctx context.Context, clientToken, f
 	for _, ap := range res.AccessPoints {
 		// check if AP exists with same client token
 		if *ap.ClientToken == clientToken {
-			return &AccessPoint{
+			accessPoint := &AccessPoint{
 				AccessPointId:      *ap.AccessPointId,
 				FileSystemId:       *ap.FileSystemId,
 				AccessPointRootDir: *ap.RootDirectory.Path,
-			}, nil
+				Tags:               parseTagsFromEfs(ap.Tags),
+			}
+			if ap.PosixUser != nil && ap.PosixUser.Uid != nil && ap.PosixUser.Gid != nil {
+				accessPoint.PosixUser = &PosixUser{Uid: *ap.PosixUser.Uid, Gid: *ap.PosixUser.Gid}
+			}
+			return accessPoint, nil
 		}
 	}
 	klog.V(2).Infof("Access point does not exist")
@@ -315,6 +332,10 @@ func (c *cloud) ListAccessPoints(ctx context.Context, fileSystemId string) (acce
 			AccessPointId: *accessPointDescription.AccessPointId,
 			FileSystemId:  *accessPointDescription.FileSystemId,
 			PosixUser:     posixUser,
+			Tags:          parseTagsFromEfs(accessPointDescription.Tags),
+		}
+		if accessPointDescription.RootDirectory != nil && accessPointDescription.RootDirectory.Path != nil {
+			accessPoint.AccessPointRootDir = *accessPointDescription.RootDirectory.Path
 		}
 		accessPoints = append(accessPoints, accessPoint)
 	}
@@ -322,6 +343,42 @@ func (c *cloud) ListAccessPoints(ctx context.Context, fileSystemId string) (acce
 	return
 }
 
+func (c *cloud) TagAccessPoint(ctx context.Context, accessPointId string, tags map[string]string) (err error) {
+	tagResourceInput := &efs.TagResourceInput{
+		ResourceId: &accessPointId,
+		Tags:       parseEfsTags(tags),
+	}
+	_, err = c.efs.TagResource(ctx, tagResourceInput)
+	if err != nil {
+		if isAccessDenied(err) {
+			return ErrAccessDenied
+		}
+		if isAccessPointNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("Failed to tag access point: %v, error: %v", accessPointId, err)
+	}
+	return nil
+}
+
+func (c *cloud) UntagAccessPoint(ctx context.Context, accessPointId string, tagKeys []string) (err error) {
+	untagResourceInput := &efs.UntagResourceInput{
+		ResourceId: &accessPointId,
+		TagKeys:    tagKeys,
+	}
+	_, err = c.efs.UntagResource(ctx, untagResourceInput)
+	if err != nil {
+		if isAccessDenied(err) {
+			return ErrAccessDenied
+		}
+		if isAccessPointNotFound(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("Failed to untag access point: %v, error: %v", accessPointId, err)
+	}
+	return nil
+}
+
 func (c *cloud) DescribeFileSystem(ctx context.Context, fileSystemId string) (fs *FileSystem, err error) {
 	describeFsInput := &efs.DescribeFileSystemsInput{FileSystemId: &fileSystemId}
 	klog.V(5).Infof("Calling DescribeFileSystems with input: %+v", *describeFsInput)
@@ -391,6 +448,20 @@ func (c *cloud) DescribeMountTargets(ctx context.Context, fileSystemId, azName s
 	}, nil
 }
 
+// ValidateCredentials makes the cheapest possible EFS API call to verify that the
+// configured credentials are usable.
+func (c *cloud) ValidateCredentials(ctx context.Context) (err error) {
+	describeFsInput := &efs.DescribeFileSystemsInput{MaxItems: aws.Int32(1)}
+	_, err = c.efs.DescribeFileSystems(ctx, describeFsInput)
+	if err != nil {
+		if isAccessDenied(err) {
+			return ErrAccessDenied
+		}
+		return fmt.Errorf("Describe File Systems failed: %v", err)
+	}
+	return nil
+}
+
 func isFileSystemNotFound(err error) bool {
 	var FileSystemNotFoundErr *types.FileSystemNotFound
 	if errors.As(err, &FileSystemNotFoundErr) {
@@ -407,6 +478,14 @@ func isAccessPointNotFound(err error) bool {
 	return false
 }
 
+func isAccessPointLimitExceeded(err error) bool {
+	var AccessPointLimitExceededErr *types.AccessPointLimitExceeded
+	if errors.As(err, &AccessPointLimitExceededErr) {
+		return true
+	}
+	return false
+}
+
 func isAccessDenied(err error) bool {
 	var apiErr smithy.APIError
 	if errors.As(err, &apiErr) {
@@ -435,6 +514,16 @@ func parseEfsTags(tagMap map[string]string) []types.Tag {
 	return efsTags
 }
 
+func parseTagsFromEfs(efsTags []types.Tag) map[string]string {
+	tags := make(map[string]string, len(efsTags))
+	for _, tag := range efsTags {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+	return tags
+}
+
 func getAvailableMountTargets(mountTargets []types.MountTargetDescription) []types.MountTargetDescription {
 	availableMountTargets := []types.MountTargetDescription{}
 	for _, mt := range mountTargets {
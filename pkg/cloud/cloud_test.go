@@ -158,6 +158,33 @@ func TestCreateAccessPoint(t *testing.T) {
 				mockCtl.Finish()
 			},
 		},
+		{
+			name: "Fail: Access Point Limit Exceeded",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockCtl)
+				c := &cloud{efs: mockEfs}
+
+				req := &AccessPointOptions{
+					FileSystemId:   fsId,
+					Uid:            uid,
+					Gid:            gid,
+					DirectoryPerms: directoryPerms,
+					DirectoryPath:  directoryPath,
+				}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any()).Return(nil,
+					&types.AccessPointLimitExceeded{
+						Message: aws.String("You have reached the maximum number of access points"),
+					})
+				_, err := c.CreateAccessPoint(ctx, clientToken, req)
+				if err != ErrAccessPointLimitExceeded {
+					t.Fatalf("Failed. Expected: %v, Actual:%v", ErrAccessPointLimitExceeded, err)
+				}
+				mockCtl.Finish()
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -564,6 +591,9 @@ func TestFindAccessPointByClientToken(t *testing.T) {
 				if res == nil {
 					t.Fatal("Result is nil")
 				}
+				if res.PosixUser == nil || res.PosixUser.Uid != Uid || res.PosixUser.Gid != Gid {
+					t.Fatalf("PosixUser mismatched. Expected: %d:%d, Actual: %+v", Uid, Gid, res.PosixUser)
+				}
 
 				mockctl.Finish()
 			},
@@ -660,6 +690,12 @@ func TestListAccessPoints(t *testing.T) {
 								Gid: aws.Int64(Gid),
 								Uid: aws.Int64(Uid),
 							},
+							RootDirectory: &types.RootDirectory{
+								Path: aws.String("/pvc-123"),
+							},
+							Tags: []types.Tag{
+								{Key: aws.String("efs.csi.aws.com/cluster"), Value: aws.String("true")},
+							},
 						},
 					},
 					NextToken: nil,
@@ -680,6 +716,14 @@ func TestListAccessPoints(t *testing.T) {
 					t.Fatalf("Expected only one AccessPoint in response but got: %v", res)
 				}
 
+				if res[0].AccessPointRootDir != "/pvc-123" {
+					t.Fatalf("Expected root directory /pvc-123 but got: %v", res[0].AccessPointRootDir)
+				}
+
+				if res[0].Tags["efs.csi.aws.com/cluster"] != "true" {
+					t.Fatalf("Expected cluster tag in response but got: %v", res[0].Tags)
+				}
+
 				mockctl.Finish()
 			},
 		},
@@ -756,6 +800,133 @@ func TestListAccessPoints(t *testing.T) {
 	}
 }
 
+func TestTagAccessPoint(t *testing.T) {
+	var (
+		accessPointId = "fsap-abcd1234xyz987"
+		tags          = map[string]string{"efs.csi.aws.com/consumer/pv-1": "default/pvc"}
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "Success",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().TagResource(gomock.Eq(ctx), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, input *efs.TagResourceInput, optFns ...func(*efs.Options)) (*efs.TagResourceOutput, error) {
+						if *input.ResourceId != accessPointId || len(input.Tags) != 1 || *input.Tags[0].Key != "efs.csi.aws.com/consumer/pv-1" {
+							t.Fatalf("Unexpected TagResource input: %+v", input)
+						}
+						return &efs.TagResourceOutput{}, nil
+					})
+				err := c.TagAccessPoint(ctx, accessPointId, tags)
+				if err != nil {
+					t.Fatalf("Tag Access Point failed: %v", err)
+				}
+
+				mockctl.Finish()
+			},
+		},
+		{
+			name: "Fail: Access Point Not Found",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+				ctx := context.Background()
+				mockEfs.EXPECT().TagResource(gomock.Eq(ctx), gomock.Any()).Return(nil,
+					&types.AccessPointNotFound{
+						Message: aws.String("Access Point not found"),
+					})
+				err := c.TagAccessPoint(ctx, accessPointId, tags)
+				if err != ErrNotFound {
+					t.Fatalf("Failed. Expected: %v, Actual:%v", ErrNotFound, err)
+				}
+				mockctl.Finish()
+			},
+		},
+		{
+			name: "Fail: Access Denied",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+				ctx := context.Background()
+				mockEfs.EXPECT().TagResource(gomock.Eq(ctx), gomock.Any()).Return(nil,
+					&smithy.GenericAPIError{
+						Code:    AccessDeniedException,
+						Message: "Access Denied",
+					})
+				err := c.TagAccessPoint(ctx, accessPointId, tags)
+				if err != ErrAccessDenied {
+					t.Fatalf("Failed. Expected: %v, Actual:%v", ErrAccessDenied, err)
+				}
+				mockctl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestUntagAccessPoint(t *testing.T) {
+	var (
+		accessPointId = "fsap-abcd1234xyz987"
+		tagKeys       = []string{"efs.csi.aws.com/consumer/pv-1"}
+	)
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "Success",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().UntagResource(gomock.Eq(ctx), gomock.Eq(&efs.UntagResourceInput{
+					ResourceId: aws.String(accessPointId),
+					TagKeys:    tagKeys,
+				})).Return(&efs.UntagResourceOutput{}, nil)
+				err := c.UntagAccessPoint(ctx, accessPointId, tagKeys)
+				if err != nil {
+					t.Fatalf("Untag Access Point failed: %v", err)
+				}
+
+				mockctl.Finish()
+			},
+		},
+		{
+			name: "Fail: Other",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+				ctx := context.Background()
+				mockEfs.EXPECT().UntagResource(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("UntagResource failed"))
+				err := c.UntagAccessPoint(ctx, accessPointId, tagKeys)
+				if err == nil {
+					t.Fatalf("UntagAccessPoint did not fail")
+				}
+				mockctl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
 func TestDescribeFileSystem(t *testing.T) {
 	var (
 		fsId = "fs-abcd1234"
@@ -1069,6 +1240,75 @@ func testResult(t *testing.T, funcName string, ret interface{}, err error, expec
 	}
 }
 
+func TestValidateCredentials(t *testing.T) {
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "Success",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().DescribeFileSystems(gomock.Eq(ctx), gomock.Any()).Return(&efs.DescribeFileSystemsOutput{}, nil).
+					Do(func(ctx context.Context, input *efs.DescribeFileSystemsInput, optFns ...func(*efs.Options)) {
+						if input.FileSystemId != nil {
+							t.Fatalf("Expected no file system ID, got %v", *input.FileSystemId)
+						}
+						if aws.ToInt32(input.MaxItems) != 1 {
+							t.Fatalf("MaxItems mismatched. Expected: %v, Actual: %v", 1, aws.ToInt32(input.MaxItems))
+						}
+					})
+				if err := c.ValidateCredentials(ctx); err != nil {
+					t.Fatalf("ValidateCredentials failed: %v", err)
+				}
+				mockctl.Finish()
+			},
+		},
+		{
+			name: "Fail: Access Denied",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().DescribeFileSystems(gomock.Eq(ctx), gomock.Any()).Return(nil,
+					&smithy.GenericAPIError{
+						Code:    AccessDeniedException,
+						Message: "Access Denied",
+					})
+				err := c.ValidateCredentials(ctx)
+				if err != ErrAccessDenied {
+					t.Fatalf("Failed. Expected: %v, Actual:%v", ErrAccessDenied, err)
+				}
+				mockctl.Finish()
+			},
+		},
+		{
+			name: "Fail: Other",
+			testFunc: func(t *testing.T) {
+				mockctl := gomock.NewController(t)
+				mockEfs := mocks.NewMockEfs(mockctl)
+				c := &cloud{efs: mockEfs}
+
+				ctx := context.Background()
+				mockEfs.EXPECT().DescribeFileSystems(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeFileSystems failed"))
+				if err := c.ValidateCredentials(ctx); err == nil {
+					t.Fatalf("ValidateCredentials did not fail")
+				}
+				mockctl.Finish()
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
 func Test_findAccessPointByPath(t *testing.T) {
 	fsId := "testFsId"
 	clientToken := "testPvcName"
@@ -1083,6 +1323,7 @@ func Test_findAccessPointByPath(t *testing.T) {
 		AccessPointId:      "testApId",
 		AccessPointRootDir: dirPath,
 		FileSystemId:       fsId,
+		Tags:               map[string]string{"efs.csi.aws.com/cluster": "true"},
 	}
 
 	type args struct {
@@ -1105,7 +1346,8 @@ func Test_findAccessPointByPath(t *testing.T) {
 			mockEfs.EXPECT().DescribeAccessPoints(gomock.Any(), gomock.Any()).Return(&efs.DescribeAccessPointsOutput{
 				AccessPoints: []types.AccessPointDescription{
 					{FileSystemId: aws.String(fsId), ClientToken: diffClientToken, AccessPointId: aws.String("differentApId"), RootDirectory: &types.RootDirectory{Path: aws.String(expectedSingleAP.AccessPointRootDir)}},
-					{FileSystemId: aws.String(fsId), ClientToken: &clientToken, AccessPointId: aws.String(expectedSingleAP.AccessPointId), RootDirectory: &types.RootDirectory{Path: aws.String(expectedSingleAP.AccessPointRootDir)}},
+					{FileSystemId: aws.String(fsId), ClientToken: &clientToken, AccessPointId: aws.String(expectedSingleAP.AccessPointId), RootDirectory: &types.RootDirectory{Path: aws.String(expectedSingleAP.AccessPointRootDir)},
+						Tags: []types.Tag{{Key: aws.String("efs.csi.aws.com/cluster"), Value: aws.String("true")}}},
 				},
 			}, nil)
 		}, wantAccessPoint: expectedSingleAP, wantErr: false},
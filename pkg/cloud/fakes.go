@@ -40,9 +40,11 @@ func (c *FakeCloudProvider) CreateAccessPoint(ctx context.Context, clientToken s
 	apId := fmt.Sprintf("fsap-%d", r.Uint64())
 	fsId := accessPointOpts.FileSystemId
 	ap = &AccessPoint{
-		AccessPointId: apId,
-		FileSystemId:  fsId,
-		CapacityGiB:   accessPointOpts.CapacityGiB,
+		AccessPointId:      apId,
+		FileSystemId:       fsId,
+		AccessPointRootDir: accessPointOpts.DirectoryPath,
+		CapacityGiB:        accessPointOpts.CapacityGiB,
+		Tags:               accessPointOpts.Tags,
 	}
 
 	c.accessPoints[clientToken] = ap
@@ -112,3 +114,32 @@ func (c *FakeCloudProvider) ListAccessPoints(ctx context.Context, fileSystemId s
 	}
 	return accessPoints, nil
 }
+
+func (c *FakeCloudProvider) TagAccessPoint(ctx context.Context, accessPointId string, tags map[string]string) error {
+	ap, err := c.DescribeAccessPoint(ctx, accessPointId)
+	if err != nil {
+		return err
+	}
+	if ap.Tags == nil {
+		ap.Tags = map[string]string{}
+	}
+	for k, v := range tags {
+		ap.Tags[k] = v
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) UntagAccessPoint(ctx context.Context, accessPointId string, tagKeys []string) error {
+	ap, err := c.DescribeAccessPoint(ctx, accessPointId)
+	if err != nil {
+		return err
+	}
+	for _, k := range tagKeys {
+		delete(ap.Tags, k)
+	}
+	return nil
+}
+
+func (c *FakeCloudProvider) ValidateCredentials(ctx context.Context) error {
+	return nil
+}
@@ -25,6 +25,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/util"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
 )
 
@@ -44,6 +46,7 @@ const (
 	DefaultGidMax         = DefaultGidMin + cloud.AccessPointPerFsLimit
 	DefaultTagKey         = "efs.csi.aws.com/cluster"
 	DefaultTagValue       = "true"
+	DirectoryMode         = "efs-dir"
 	DirectoryPerms        = "directoryPerms"
 	EnsureUniqueDirectory = "ensureUniqueDirectory"
 	FsId                  = "fileSystemId"
@@ -56,19 +59,51 @@ const (
 	PvcName               = "csi.storage.k8s.io/pvc/name"
 	PvcNamespace          = "csi.storage.k8s.io/pvc/namespace"
 	RoleArn               = "awsRoleArn"
+	SharedAccessPointName = "sharedAccessPointName"
 	SubPathPattern        = "subPathPattern"
 	TempMountPathPrefix   = "/var/lib/csi/pv"
 	Uid                   = "uid"
 	ReuseAccessPointKey   = "reuseAccessPoint"
+	ReuseIdentityKey      = "reuseAccessPointIdentity"
 	PvcNameKey            = "csi.storage.k8s.io/pvc/name"
 	CrossAccount          = "crossaccount"
 )
 
+// Values of --foreign-access-point-deletion and --protected-access-point-deletion, which decide what DeleteVolume
+// does with the access points it must not delete.
+const (
+	// AccessPointDeletionDetach deletes the volume and keeps its access point.
+	AccessPointDeletionDetach = "detach"
+	// AccessPointDeletionRefuse fails DeleteVolume.
+	AccessPointDeletionRefuse = "refuse"
+)
+
+const (
+	// DeletionProtectionAnnotation on a PVC protects the access point of its volume from deletion.
+	DeletionProtectionAnnotation = "efs.csi.aws.com/deletion-protection"
+	// DeletionProtectionTagKey is set on the access points protected from deletion.
+	DeletionProtectionTagKey = "efs.csi.aws.com/deletion-protection"
+	// AllowDeletionProtection is the StorageClass parameter opting its volumes in to DeletionProtectionAnnotation.
+	AllowDeletionProtection = "allowDeletionProtection"
+)
+
+// Values of the reuseAccessPointIdentity parameter, which picks the PVC metadata the client token of reused access
+// points is derived from.
+const (
+	ReuseIdentityName                  = "name"
+	ReuseIdentityNamespacedName        = "namespacedName"
+	ReuseIdentityClusterNamespacedName = "clusterNamespacedName"
+	// ReuseIdentityAnnotationPrefix is followed by the key of the PVC annotation holding the identity.
+	ReuseIdentityAnnotationPrefix = "annotation:"
+)
+
 var (
 	// controllerCaps represents the capability of controller service
 	controllerCaps = []csi.ControllerServiceCapability_RPC_Type{
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 	}
+	// archivedDirPrefix is prepended to the name of the directories of deleted efs-dir volumes that are archived.
+	archivedDirPrefix = ".archived-"
 	// subPathPatternComponents shows the elements that we allow to be in the construction of the root directory
 	// of the access point, as well as the values we need to extract them from the Volume Parameters.
 	subPathPatternComponents = map[string]string{
@@ -95,7 +130,10 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 			return nil, status.Error(codes.InvalidArgument, "Invalid value for reuseAccessPoint parameter")
 		}
 		if reuseAccessPoint {
-			clientToken = get64LenHash(volumeParams[PvcNameKey])
+			clientToken, err = d.getReuseClientToken(ctx, volumeParams)
+			if err != nil {
+				return nil, err
+			}
 			klog.V(5).Infof("Client token : %s", clientToken)
 		}
 	}
@@ -136,13 +174,31 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	if value, ok := volumeParams[ProvisioningMode]; ok {
 		provisioningMode = value
 		//TODO: Add FS provisioning mode check when implemented
-		if provisioningMode != AccessPointMode {
-			errStr := "Provisioning mode " + provisioningMode + " is not supported. Only Access point provisioning: 'efs-ap' is supported"
+		if provisioningMode != AccessPointMode && provisioningMode != DirectoryMode {
+			errStr := "Provisioning mode " + provisioningMode + " is not supported. Only Access point provisioning: 'efs-ap' and directory provisioning: 'efs-dir' are supported"
 			return nil, status.Error(codes.InvalidArgument, errStr)
 		}
 	} else {
 		return nil, status.Errorf(codes.InvalidArgument, "Missing %v parameter", ProvisioningMode)
 	}
+	if provisioningMode == DirectoryMode && reuseAccessPoint {
+		return nil, status.Errorf(codes.InvalidArgument, "Parameter %v is not supported with provisioning mode %v", ReuseAccessPointKey, DirectoryMode)
+	}
+
+	// Volumes with the same sharedAccessPointName use the same access point, which is deleted with the last of them.
+	var sharedAccessPointName string
+	if value, ok := volumeParams[SharedAccessPointName]; ok {
+		if provisioningMode != AccessPointMode || reuseAccessPoint {
+			return nil, status.Errorf(codes.InvalidArgument, "Parameter %v is only supported with provisioning mode %v and without %v", SharedAccessPointName, AccessPointMode, ReuseAccessPointKey)
+		}
+		sharedAccessPointName, err = interpolateRootDirectoryName(value, volumeParams)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Trim(sharedAccessPointName, " /") == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "Parameter %v cannot be empty", SharedAccessPointName)
+		}
+	}
 
 	accessPointsOptions := &cloud.AccessPointOptions{
 		CapacityGiB: volSize,
@@ -157,17 +213,37 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 		return nil, status.Errorf(codes.InvalidArgument, "Missing %v parameter", FsId)
 	}
 
-	localCloud, roleArn, crossAccountDNSEnabled, err = getCloud(req.GetSecrets(), d)
+	// The uid and gid are checked once they are allocated, or once the access point is found.
+	policyRequest := provisioningRequest{
+		fileSystemId: accessPointsOptions.FileSystemId,
+		basePath:     volumeParams[BasePath],
+	}
+	if err := d.checkProvisioningPolicy(ctx, volumeParams, policyRequest); err != nil {
+		return nil, err
+	}
+
+	localCloud, roleArn, crossAccountDNSEnabled, err = getCloud(req.GetSecrets(), d, volumeParams[PvcNamespace], accessPointsOptions.FileSystemId)
 	if err != nil {
 		return nil, err
 	}
 
-	var accessPoint *cloud.AccessPoint
-	//if reuseAccessPoint is true, check for AP with same Root Directory exists in efs
+	if sharedAccessPointName != "" {
+		clientToken = sharedAccessPointClientToken(accessPointsOptions.FileSystemId, sharedAccessPointName)
+		defer d.sharedAccessPointLocks.lock(clientToken)()
+	}
+
+	var (
+		accessPoint *cloud.AccessPoint
+		volumeId    string
+	)
+	//if reuseAccessPoint is true or the access point is shared, check for AP with same Root Directory exists in efs
 	// if found reuse that AP
-	if reuseAccessPoint {
+	if reuseAccessPoint || sharedAccessPointName != "" {
 		existingAP, err := localCloud.FindAccessPointByClientToken(ctx, clientToken, accessPointsOptions.FileSystemId)
 		if err != nil {
+			if err == cloud.ErrAccessDenied {
+				return nil, d.provisioningFailed(ctx, volumeParams, ReasonAccessDenied, fmt.Errorf("failed to find access point: %v", err))
+			}
 			return nil, fmt.Errorf("failed to find access point: %v", err)
 		}
 		if existingAP != nil {
@@ -177,6 +253,17 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 				AccessPointId: existingAP.AccessPointId,
 				FileSystemId:  existingAP.FileSystemId,
 				CapacityGiB:   accessPointsOptions.CapacityGiB,
+				Tags:          existingAP.Tags,
+			}
+
+			// The access point may have been created for another namespace, or before the policy changed.
+			policyRequest.identity = &cloud.PosixUser{Uid: -1, Gid: -1}
+			if existingAP.PosixUser != nil {
+				policyRequest.identity = existingAP.PosixUser
+			}
+			policyRequest.rootDir = existingAP.AccessPointRootDir
+			if err := d.checkProvisioningPolicy(ctx, volumeParams, policyRequest); err != nil {
+				return nil, err
 			}
 		}
 	}
@@ -193,6 +280,21 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 				tags[k] = v
 			}
 		}
+		if sharedAccessPointName != "" {
+			tags[SharedAccessPointNameTagKey] = sharedAccessPointName
+		}
+		if d.clusterId != "" {
+			tags[OwnerClusterTagKey] = d.clusterId
+		}
+		if provisioningMode == AccessPointMode {
+			protected, err := d.deletionProtectionRequested(ctx, volumeParams)
+			if err != nil {
+				return nil, err
+			}
+			if protected {
+				tags[DeletionProtectionTagKey] = "true"
+			}
+		}
 
 		accessPointsOptions.Tags = tags
 
@@ -255,6 +357,15 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 
 		if value, ok := volumeParams[DirectoryPerms]; ok {
 			accessPointsOptions.DirectoryPerms = value
+		} else if provisioningMode == DirectoryMode {
+			return nil, status.Errorf(codes.InvalidArgument, "Missing %v parameter", DirectoryPerms)
+		}
+		var directoryPerms uint64
+		if provisioningMode == DirectoryMode {
+			directoryPerms, err = strconv.ParseUint(accessPointsOptions.DirectoryPerms, 8, 32)
+			if err != nil || directoryPerms > 0777 {
+				return nil, status.Errorf(codes.InvalidArgument, "Failed to parse invalid %v: %v", DirectoryPerms, accessPointsOptions.DirectoryPerms)
+			}
 		}
 
 		// Storage class parameter `az` will be used to fetch preferred mount target for cross account mount.
@@ -267,44 +378,57 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 		}
 
 		// Check if file system exists. Describe FS or List APs handle appropriate error codes
-		// With dynamic uid/gid provisioning we can save a call to describe FS, as list APs fails if FS ID does not exist
+		// With dynamic uid/gid provisioning we can save a call to describe FS, as list APs fails if FS ID does not exist.
+		// Directories are not allocated a gid, they keep the owner of the controller unless uid/gid is set.
+		allocateGid := provisioningMode == AccessPointMode && (uid == -1 || gid == -1)
 		var accessPoints []*cloud.AccessPoint
-		if uid == -1 || gid == -1 {
+		if allocateGid {
 			accessPoints, err = localCloud.ListAccessPoints(ctx, accessPointsOptions.FileSystemId)
 		} else {
 			_, err = localCloud.DescribeFileSystem(ctx, accessPointsOptions.FileSystemId)
 		}
 		if err != nil {
 			if err == cloud.ErrAccessDenied {
-				return nil, status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err)
+				return nil, d.provisioningFailed(ctx, volumeParams, ReasonAccessDenied,
+					status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
 			}
 			if err == cloud.ErrNotFound {
-				return nil, status.Errorf(codes.InvalidArgument, "File System does not exist: %v", err)
+				return nil, d.provisioningFailed(ctx, volumeParams, ReasonFileSystemNotFound,
+					status.Errorf(codes.InvalidArgument, "File System does not exist: %v", err))
 			}
 			return nil, status.Errorf(codes.Internal, "Failed to fetch Access Points or Describe File System: %v", err)
 		}
 
-		var allocatedGid int64
-		if uid == -1 || gid == -1 {
-			allocatedGid, err = d.gidAllocator.getNextGid(accessPointsOptions.FileSystemId, accessPoints, gidMin, gidMax)
+		if allocateGid {
+			allocatedGid, err := d.gidAllocator.getNextGid(accessPointsOptions.FileSystemId, accessPoints, gidMin, gidMax)
 			if err != nil {
-				return nil, err
+				return nil, d.provisioningFailed(ctx, volumeParams, ReasonGidRangeExhausted, err)
+			}
+			if uid == -1 {
+				uid = allocatedGid
+			}
+			if gid == -1 {
+				gid = allocatedGid
 			}
-		}
-		if uid == -1 {
-			uid = allocatedGid
-		}
-		if gid == -1 {
-			gid = allocatedGid
 		}
 
 		if value, ok := volumeParams[BasePath]; ok {
 			basePath = value
 		}
 
+		// The unique suffix of directories must not change when CreateVolume is retried, since a directory cannot be
+		// looked up by client token like an access point.
+		uniqueSuffix := uuid.New().String()
+		if provisioningMode == DirectoryMode {
+			uniqueSuffix = uuid.NewSHA1(uuid.NameSpaceURL, []byte(volName)).String()
+		}
+
 		rootDirName := volName
 		// Check if a custom structure should be imposed on the access point directory
-		if value, ok := volumeParams[SubPathPattern]; ok {
+		if sharedAccessPointName != "" {
+			klog.Infof("Using shared access point name for access point directory.")
+			rootDirName = sharedAccessPointName
+		} else if value, ok := volumeParams[SubPathPattern]; ok {
 			// Try and construct the root directory and check it only contains supported components
 			val, err := interpolateRootDirectoryName(value, volumeParams)
 			if err == nil {
@@ -315,11 +439,11 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 						klog.Infof("Not appending PVC UID to path.")
 					} else {
 						klog.Infof("Appending PVC UID to path.")
-						rootDirName = fmt.Sprintf("%s-%s", val, uuid.New().String())
+						rootDirName = fmt.Sprintf("%s-%s", val, uniqueSuffix)
 					}
 				} else {
 					klog.Infof("Appending PVC UID to path.")
-					rootDirName = fmt.Sprintf("%s-%s", val, uuid.New().String())
+					rootDirName = fmt.Sprintf("%s-%s", val, uniqueSuffix)
 				}
 			} else {
 				return nil, err
@@ -329,28 +453,66 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 		}
 
 		rootDir := path.Join("/", basePath, rootDirName)
-		if ok, err := validateEfsPathRequirements(rootDir); !ok {
+		policyRequest.identity = &cloud.PosixUser{Uid: uid, Gid: gid}
+		policyRequest.rootDir = rootDir
+		if err := d.checkProvisioningPolicy(ctx, volumeParams, policyRequest); err != nil {
 			return nil, err
 		}
-		klog.Infof("Using %v as the access point directory.", rootDir)
-
-		accessPointsOptions.Uid = uid
-		accessPointsOptions.Gid = gid
-		accessPointsOptions.DirectoryPath = rootDir
 
-		accessPoint, err = localCloud.CreateAccessPoint(ctx, clientToken, accessPointsOptions)
-		if err != nil {
-			if err == cloud.ErrAccessDenied {
-				return nil, status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err)
+		if provisioningMode == DirectoryMode {
+			if rootDir == "/" {
+				return nil, status.Errorf(codes.InvalidArgument, "Proposed path '%s' is the root of the file system", rootDir)
 			}
-			if err == cloud.ErrAlreadyExists {
-				return nil, status.Errorf(codes.AlreadyExists, "Access Point already exists")
+			klog.Infof("Using %v as the volume directory.", rootDir)
+			err = d.createVolumeDirectory(ctx, localCloud, accessPointsOptions.FileSystemId, rootDir, os.FileMode(directoryPerms), uid, gid, volName, roleArn, crossAccountDNSEnabled)
+			if err != nil {
+				return nil, err
 			}
-			return nil, status.Errorf(codes.Internal, "Failed to create Access point in File System %v : %v", accessPointsOptions.FileSystemId, err)
+			volumeId = provisionedDirectoryVolumeId(accessPointsOptions.FileSystemId, rootDir)
+		} else {
+			if ok, err := validateEfsPathRequirements(rootDir); !ok {
+				return nil, d.provisioningFailed(ctx, volumeParams, ReasonPathTooLong, err)
+			}
+			klog.Infof("Using %v as the access point directory.", rootDir)
+
+			accessPointsOptions.Uid = uid
+			accessPointsOptions.Gid = gid
+			accessPointsOptions.DirectoryPath = rootDir
+
+			accessPoint, err = localCloud.CreateAccessPoint(ctx, clientToken, accessPointsOptions)
+			if err != nil {
+				if err == cloud.ErrAccessDenied {
+					return nil, d.provisioningFailed(ctx, volumeParams, ReasonAccessDenied,
+						status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
+				}
+				if err == cloud.ErrAccessPointLimitExceeded {
+					return nil, d.provisioningFailed(ctx, volumeParams, ReasonAccessPointLimitReached,
+						status.Errorf(codes.ResourceExhausted, "Failed to create Access point in File System %v : %v", accessPointsOptions.FileSystemId, err))
+				}
+				if err == cloud.ErrAlreadyExists {
+					return nil, status.Errorf(codes.AlreadyExists, "Access Point already exists")
+				}
+				return nil, status.Errorf(codes.Internal, "Failed to create Access point in File System %v : %v", accessPointsOptions.FileSystemId, err)
+			}
+		}
+	}
+	if accessPoint != nil {
+		volumeId = accessPointsOptions.FileSystemId + "::" + accessPoint.AccessPointId
+	}
+	if sharedAccessPointName != "" {
+		if accessPoint.Tags == nil {
+			accessPoint.Tags = accessPointsOptions.Tags
 		}
+		if err := d.addSharedAccessPointConsumer(ctx, localCloud, accessPoint, volName, volumeParams); err != nil {
+			return nil, err
+		}
+		volumeId += ":" + volName
 	}
 
 	volContext := map[string]string{}
+	if pvName := volumeParams[PvName]; pvName != "" {
+		volContext[VolumeContextPvName] = pvName
+	}
 
 	// Enable cross-account dns resolution or fetch mount target Ip for cross-account mount
 	if roleArn != "" {
@@ -374,7 +536,7 @@ func (d *Driver) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest)
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			CapacityBytes: volSize,
-			VolumeId:      accessPointsOptions.FileSystemId + "::" + accessPoint.AccessPointId,
+			VolumeId:      volumeId,
 			VolumeContext: volContext,
 		},
 	}, nil
@@ -388,85 +550,113 @@ func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest)
 		err                    error
 	)
 
-	localCloud, roleArn, crossAccountDNSEnabled, err = getCloud(req.GetSecrets(), d)
-	if err != nil {
-		return nil, err
-	}
-
 	klog.V(4).Infof("DeleteVolume: called with args %+v", util.SanitizeRequest(*req))
 	volId := req.GetVolumeId()
 	if volId == "" {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
 	}
 
-	fileSystemId, _, accessPointId, err := parseVolumeId(volId)
+	fileSystemId, subpath, accessPointId, err := parseVolumeId(volId)
 	if err != nil {
 		//Returning success for an invalid volume ID. See here - https://github.com/kubernetes-csi/csi-test/blame/5deb83d58fea909b2895731d43e32400380aae3c/pkg/sanity/controller.go#L733
 		klog.V(5).Infof("DeleteVolume: Failed to parse volumeID: %v, err: %v, returning success", volId, err)
 		return &csi.DeleteVolumeResponse{}, nil
 	}
 
+	namespace, err := d.volumeNamespace(ctx, volId)
+	if err != nil {
+		return nil, err
+	}
+	localCloud, roleArn, crossAccountDNSEnabled, err = getCloud(req.GetSecrets(), d, namespace, fileSystemId)
+	if err != nil {
+		return nil, err
+	}
+
 	//TODO: Add Delete File System when FS provisioning is implemented
 	if accessPointId != "" {
 
-		// Delete access point root directory if delete-access-point-root-dir is set.
-		if d.deleteAccessPointRootDir {
-			// Check if Access point exists.
-			// If access point exists, retrieve its root directory and delete it/
-			accessPoint, err := localCloud.DescribeAccessPoint(ctx, accessPointId)
-			if err != nil {
-				if err == cloud.ErrAccessDenied {
-					return nil, status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err)
-				}
-				if err == cloud.ErrNotFound {
-					klog.V(5).Infof("DeleteVolume: Access Point %v not found, returning success", accessPointId)
-					return &csi.DeleteVolumeResponse{}, nil
-				}
-				return nil, status.Errorf(codes.Internal, "Could not get describe Access Point: %v , error: %v", accessPointId, err)
+		// Describe the access point for the checks below and to find its root directory.
+		accessPoint, err := d.describeDeletedAccessPoint(ctx, localCloud, volId, accessPointId)
+		if err != nil || accessPoint == nil {
+			return &csi.DeleteVolumeResponse{}, err
+		}
+
+		// A shared access point is only deleted with the last volume using it. It is described again once locked,
+		// as other volumes may have started or stopped using it meanwhile.
+		consumer := sharedAccessPointConsumer(volId)
+		lastConsumer := true
+		if consumer != "" {
+			defer d.sharedAccessPointLocks.lock(sharedAccessPointClientToken(fileSystemId, accessPoint.Tags[SharedAccessPointNameTagKey]))()
+			accessPoint, err = d.describeDeletedAccessPoint(ctx, localCloud, volId, accessPointId)
+			if err != nil || accessPoint == nil {
+				return &csi.DeleteVolumeResponse{}, err
 			}
+			others := sharedAccessPointConsumers(accessPoint.Tags)
+			if _, ok := accessPoint.Tags[sharedAccessPointConsumerTagKey(consumer)]; ok {
+				others--
+			}
+			lastConsumer = others == 0
+		}
 
-			//Mount File System at it root and delete access point root directory
-			mountOptions := []string{"tls", "iam"}
-			if roleArn != "" {
-				if crossAccountDNSEnabled {
-					// Connect via dns rather than mounttargetip
-					mountOptions = append(mountOptions, CrossAccount)
-				} else {
-					mountTarget, err := localCloud.DescribeMountTargets(ctx, fileSystemId, "")
-					if err == nil {
-						mountOptions = append(mountOptions, MountTargetIp+"="+mountTarget.IPAddress)
-					} else {
-						klog.Warningf("Failed to describe mount targets for file system %v. Skip using `mounttargetip` mount option: %v", fileSystemId, err)
-					}
-				}
+		// Refuse to delete the volume before changing the consumers of the access point, so that DeleteVolume is
+		// refused the same way when it is retried.
+		foreign := isForeignAccessPoint(d.clusterId, accessPoint)
+		if foreign && d.foreignAccessPointDeletion == AccessPointDeletionRefuse {
+			return nil, d.deletionFailed(ctx, volId, ReasonAccessPointOwnedByOtherCluster,
+				status.Errorf(codes.FailedPrecondition, "Access Point %v is owned by cluster %v", accessPointId, accessPointOwner(accessPoint)))
+		}
+		protected := accessPoint.Tags[DeletionProtectionTagKey] == "true"
+		if protected && lastConsumer && d.protectedAccessPointDeletion != AccessPointDeletionDetach {
+			return nil, d.deletionFailed(ctx, volId, ReasonAccessPointDeletionProtected,
+				status.Errorf(codes.FailedPrecondition, "Access Point %v is protected from deletion by tag %v, remove the tag to delete it", accessPointId, DeletionProtectionTagKey))
+		}
+
+		// The volumes of a shared access point owned by another cluster stop using it too, so that its owner can
+		// delete it once no volume uses it.
+		if consumer != "" {
+			consumers, err := d.removeSharedAccessPointConsumer(ctx, localCloud, volId, accessPointId, consumer)
+			if err != nil {
+				return nil, err
 			}
+			if consumers > 0 {
+				klog.V(2).Infof("DeleteVolume: Access Point %v is still used by %d volumes, keeping it", accessPointId, consumers)
+				return &csi.DeleteVolumeResponse{}, nil
+			}
+		}
 
+		// Do not delete access points owned by another cluster sharing the file system.
+		if foreign {
+			klog.Infof("DeleteVolume: Access Point %v is owned by cluster %v, detaching volume %v without deleting it", accessPointId, accessPointOwner(accessPoint), volId)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+
+		// Do not delete access points protected from deletion.
+		if protected {
+			klog.Infof("DeleteVolume: Access Point %v is protected from deletion, detaching volume %v without deleting it", accessPointId, volId)
+			return &csi.DeleteVolumeResponse{}, nil
+		}
+
+		// Delete access point root directory if delete-access-point-root-dir is set.
+		if d.deleteAccessPointRootDir || featureGates.Enabled(DeleteAccessPointRootDir) {
+			//Mount File System at it root and delete access point root directory
 			target := TempMountPathPrefix + "/" + accessPointId
-			if err := d.mounter.MakeDir(target); err != nil {
-				return nil, status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
-			}
-			if err := d.mounter.Mount(fileSystemId, target, "efs", mountOptions); err != nil {
-				os.Remove(target)
-				return nil, status.Errorf(codes.Internal, "Could not mount %q at %q: %v", fileSystemId, target, err)
+			if err := d.mountFileSystemRoot(ctx, localCloud, fileSystemId, target, roleArn, crossAccountDNSEnabled); err != nil {
+				return nil, err
 			}
 			err = os.RemoveAll(target + accessPoint.AccessPointRootDir)
 			if err != nil {
 				return nil, status.Errorf(codes.Internal, "Could not delete access point root directory %q: %v", accessPoint.AccessPointRootDir, err)
 			}
-			err = d.mounter.Unmount(target)
-			if err != nil {
-				return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
-			}
-			err = os.RemoveAll(target)
-			if err != nil {
-				return nil, status.Errorf(codes.Internal, "Could not delete %q: %v", target, err)
+			if err := d.unmountFileSystemRoot(target); err != nil {
+				return nil, err
 			}
 		}
 
 		// Delete access point
 		if err = localCloud.DeleteAccessPoint(ctx, accessPointId); err != nil {
 			if err == cloud.ErrAccessDenied {
-				return nil, status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err)
+				return nil, d.deletionFailed(ctx, volId, ReasonAccessDenied,
+					status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
 			}
 			if err == cloud.ErrNotFound {
 				klog.V(5).Infof("DeleteVolume: Access Point not found, returning success")
@@ -474,6 +664,21 @@ func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest)
 			}
 			return nil, status.Errorf(codes.Internal, "Failed to Delete volume %v: %v", volId, err)
 		}
+	} else if isProvisionedDirectory(volId) && path.Clean("/"+subpath) != "/" {
+		// Volumes provisioned in efs-dir mode are a directory of the file system. The directories of static volumes,
+		// fs-id:/path, are not deleted.
+		dir := path.Clean("/" + subpath)
+		target := TempMountPathPrefix + "/" + get64LenHash(volId)
+		if err := d.mountFileSystemRoot(ctx, localCloud, fileSystemId, target, roleArn, crossAccountDNSEnabled); err != nil {
+			return nil, err
+		}
+		err = removeVolumeDirectory(target, dir, d.archiveDeletedDirectories)
+		if unmountErr := d.unmountFileSystemRoot(target); err == nil {
+			err = unmountErr
+		}
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		return nil, status.Errorf(codes.NotFound, "Failed to find access point for volume: %v", volId)
 	}
@@ -481,6 +686,24 @@ func (d *Driver) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest)
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
+// describeDeletedAccessPoint describes the access point of volId for DeleteVolume. It returns nil if the access
+// point no longer exists.
+func (d *Driver) describeDeletedAccessPoint(ctx context.Context, localCloud cloud.Cloud, volId, accessPointId string) (*cloud.AccessPoint, error) {
+	accessPoint, err := localCloud.DescribeAccessPoint(ctx, accessPointId)
+	if err != nil {
+		if err == cloud.ErrAccessDenied {
+			return nil, d.deletionFailed(ctx, volId, ReasonAccessDenied,
+				status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
+		}
+		if err == cloud.ErrNotFound {
+			klog.V(5).Infof("DeleteVolume: Access Point %v not found, returning success", accessPointId)
+			return nil, nil
+		}
+		return nil, status.Errorf(codes.Internal, "Could not get describe Access Point: %v , error: %v", accessPointId, err)
+	}
+	return accessPoint, nil
+}
+
 func (d *Driver) ControllerPublishVolume(ctx context.Context, req *csi.ControllerPublishVolumeRequest) (*csi.ControllerPublishVolumeResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "")
 }
@@ -560,7 +783,113 @@ func (d *Driver) ControllerGetVolume(ctx context.Context, req *csi.ControllerGet
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
-func getCloud(secrets map[string]string, driver *Driver) (cloud.Cloud, string, bool, error) {
+// mountFileSystemRoot mounts the root directory of fileSystemId at target, to manage the directories of access points.
+func (d *Driver) mountFileSystemRoot(ctx context.Context, localCloud cloud.Cloud, fileSystemId, target, roleArn string, crossAccountDNSEnabled bool) error {
+	mountOptions := []string{"tls", "iam"}
+	if roleArn != "" {
+		if crossAccountDNSEnabled {
+			// Connect via dns rather than mounttargetip
+			mountOptions = append(mountOptions, CrossAccount)
+		} else {
+			mountTarget, err := localCloud.DescribeMountTargets(ctx, fileSystemId, "")
+			if err == nil {
+				mountOptions = append(mountOptions, MountTargetIp+"="+mountTarget.IPAddress)
+			} else {
+				klog.Warningf("Failed to describe mount targets for file system %v. Skip using `mounttargetip` mount option: %v", fileSystemId, err)
+			}
+		}
+	}
+
+	if err := d.mounter.MakeDir(target); err != nil {
+		return status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
+	}
+	if err := d.mounter.Mount(fileSystemId, target, "efs", mountOptions); err != nil {
+		os.Remove(target)
+		return status.Errorf(codes.Internal, "Could not mount %q at %q: %v", fileSystemId, target, err)
+	}
+	return nil
+}
+
+// unmountFileSystemRoot unmounts and removes a target of mountFileSystemRoot.
+func (d *Driver) unmountFileSystemRoot(target string) error {
+	if err := d.mounter.Unmount(target); err != nil {
+		return status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return status.Errorf(codes.Internal, "Could not delete %q: %v", target, err)
+	}
+	return nil
+}
+
+// createVolumeDirectory creates the directory of a volume provisioned in efs-dir mode on the file system.
+func (d *Driver) createVolumeDirectory(ctx context.Context, localCloud cloud.Cloud, fileSystemId, dir string, perms os.FileMode, uid, gid int64, volName, roleArn string, crossAccountDNSEnabled bool) error {
+	target := TempMountPathPrefix + "/" + volName
+	if err := d.mountFileSystemRoot(ctx, localCloud, fileSystemId, target, roleArn, crossAccountDNSEnabled); err != nil {
+		return err
+	}
+	err := makeVolumeDirectory(target, dir, perms, uid, gid)
+	if unmountErr := d.unmountFileSystemRoot(target); err == nil {
+		err = unmountErr
+	}
+	return err
+}
+
+// makeVolumeDirectory creates dir under root with perms, owned by uid and gid unless they are -1. An existing dir
+// is kept, so that CreateVolume can be retried.
+func makeVolumeDirectory(root, dir string, perms os.FileMode, uid, gid int64) error {
+	target := root + dir
+	if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
+		return status.Errorf(codes.Internal, "Could not create parent directories of %q: %v", dir, err)
+	}
+	if err := os.Mkdir(target, perms); err != nil && !os.IsExist(err) {
+		return status.Errorf(codes.Internal, "Could not create directory %q: %v", dir, err)
+	}
+	// Mkdir applies the umask of the controller.
+	if err := os.Chmod(target, perms); err != nil {
+		return status.Errorf(codes.Internal, "Could not set permissions of directory %q: %v", dir, err)
+	}
+	if uid != -1 || gid != -1 {
+		if err := os.Chown(target, int(uid), int(gid)); err != nil {
+			return status.Errorf(codes.Internal, "Could not set owner of directory %q: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+// provisionedDirectoryVolumeId returns the ID of a volume provisioned in efs-dir mode, fs-id:/path::efs-dir. The
+// fourth field tells it apart from a static volume of the same directory, which DeleteVolume must not delete.
+func provisionedDirectoryVolumeId(fileSystemId, dir string) string {
+	return fileSystemId + ":" + dir + "::" + DirectoryMode
+}
+
+// isProvisionedDirectory returns whether volumeId is of a volume provisioned in efs-dir mode.
+func isProvisionedDirectory(volumeId string) bool {
+	tokens := strings.Split(volumeId, ":")
+	return len(tokens) == 4 && tokens[1] != "" && tokens[2] == "" && tokens[3] == DirectoryMode
+}
+
+// removeVolumeDirectory deletes dir under root, or renames it with archivedDirPrefix if archive is set. A missing
+// dir has already been removed.
+func removeVolumeDirectory(root, dir string, archive bool) error {
+	if !archive {
+		if err := os.RemoveAll(root + dir); err != nil {
+			return status.Errorf(codes.Internal, "Could not delete directory %q: %v", dir, err)
+		}
+		return nil
+	}
+	archived := path.Join(path.Dir(dir), fmt.Sprintf("%s%s-%d", archivedDirPrefix, path.Base(dir), time.Now().Unix()))
+	if err := os.Rename(root+dir, root+archived); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return status.Errorf(codes.Internal, "Could not archive directory %q: %v", dir, err)
+	}
+	klog.Infof("Archived directory %s as %s", dir, archived)
+	return nil
+}
+
+// getCloud returns the cloud of the role mapped to namespace or fileSystemId, or else of the role in the secrets.
+func getCloud(secrets map[string]string, driver *Driver, namespace, fileSystemId string) (cloud.Cloud, string, bool, error) {
 
 	var localCloud cloud.Cloud
 	var roleArn string
@@ -581,6 +910,18 @@ func getCloud(secrets map[string]string, driver *Driver) (cloud.Cloud, string, b
 		crossAccountDNSEnabled = false
 	}
 
+	// The roles mapped by the platform team cannot be replaced by another role in the secrets.
+	if mapped := driver.roleMapping.lookup(namespace, fileSystemId); mapped != nil {
+		if roleArn != "" && roleArn != mapped.RoleArn {
+			return nil, "", false, status.Errorf(codes.PermissionDenied, "Role %v in the secrets is not the role mapped to namespace %q or file system %v", roleArn, namespace, fileSystemId)
+		}
+		roleArn = mapped.RoleArn
+		if _, ok := secrets[CrossAccount]; !ok {
+			crossAccountDNSEnabled = mapped.CrossAccount
+		}
+		klog.V(4).Infof("Using role %v mapped to namespace %q or file system %v", roleArn, namespace, fileSystemId)
+	}
+
 	if roleArn != "" {
 		localCloud, err = cloud.NewCloudWithRole(roleArn)
 		if err != nil {
@@ -603,6 +944,12 @@ func interpolateRootDirectoryName(rootDirectoryPath string, volumeParams map[str
 			"Path specified \"%v\" contains invalid elements. Can only contain %v", rootDirectoryPath,
 			getSupportedComponentNames())
 	}
+	// The PVC names and annotations interpolated into the path must not lead out of the base path.
+	for _, segment := range strings.Split(result, "/") {
+		if segment == ".." {
+			return "", status.Errorf(codes.InvalidArgument, "Path %q interpolated from %q cannot contain '..'", result, rootDirectoryPath)
+		}
+	}
 	return result, nil
 }
 
@@ -641,6 +988,85 @@ func validateEfsPathRequirements(proposedPath string) (bool, error) {
 	}
 }
 
+// getReuseClientToken derives the client token of a reused access point from the PVC metadata picked by the
+// reuseAccessPointIdentity parameter, so that the same PVC finds the same access point from another cluster.
+func (d *Driver) getReuseClientToken(ctx context.Context, volumeParams map[string]string) (string, error) {
+	identity := volumeParams[ReuseIdentityKey]
+	if identity == "" {
+		identity = ReuseIdentityName
+	}
+
+	pvcName, pvcNamespace := volumeParams[PvcNameKey], volumeParams[PvcNamespace]
+	if pvcName == "" {
+		return "", status.Errorf(codes.InvalidArgument, "Parameter %v requires the PVC name in %v, run the external-provisioner with --extra-create-metadata", ReuseAccessPointKey, PvcNameKey)
+	}
+	if identity != ReuseIdentityName && pvcNamespace == "" {
+		return "", status.Errorf(codes.InvalidArgument, "%v %v requires the PVC namespace in %v, run the external-provisioner with --extra-create-metadata", ReuseIdentityKey, identity, PvcNamespace)
+	}
+
+	switch {
+	case identity == ReuseIdentityName:
+		return get64LenHash(pvcName), nil
+	case identity == ReuseIdentityNamespacedName:
+		return get64LenHash(pvcNamespace + "/" + pvcName), nil
+	case identity == ReuseIdentityClusterNamespacedName:
+		if d.clusterId == "" {
+			return "", status.Errorf(codes.InvalidArgument, "%v %v requires the controller to be started with --cluster-id", ReuseIdentityKey, identity)
+		}
+		return get64LenHash(d.clusterId + "/" + pvcNamespace + "/" + pvcName), nil
+	case strings.HasPrefix(identity, ReuseIdentityAnnotationPrefix):
+		annotation := strings.TrimPrefix(identity, ReuseIdentityAnnotationPrefix)
+		if annotation == "" {
+			return "", status.Errorf(codes.InvalidArgument, "%v %v is missing the annotation key", ReuseIdentityKey, identity)
+		}
+		if d.kubeClient == nil {
+			return "", status.Errorf(codes.FailedPrecondition, "%v %v requires access to the Kubernetes API", ReuseIdentityKey, identity)
+		}
+		pvc, err := d.kubeClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "Could not get PVC %s/%s for %v: %v", pvcNamespace, pvcName, ReuseIdentityKey, err)
+		}
+		value := pvc.Annotations[annotation]
+		if value == "" {
+			return "", status.Errorf(codes.InvalidArgument, "PVC %s/%s has no annotation %v required by %v", pvcNamespace, pvcName, annotation, ReuseIdentityKey)
+		}
+		return get64LenHash(value), nil
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "Invalid value %v for %v, expected %v, %v, %v or %v<key>", identity, ReuseIdentityKey,
+			ReuseIdentityName, ReuseIdentityNamespacedName, ReuseIdentityClusterNamespacedName, ReuseIdentityAnnotationPrefix)
+	}
+}
+
+// deletionProtectionRequested tells whether the PVC of a volume is annotated with DeletionProtectionAnnotation. The
+// PVC is only looked up if the StorageClass sets AllowDeletionProtection, so that provisioning does not depend on the
+// API server otherwise, and is only known when the external-provisioner runs with --extra-create-metadata.
+func (d *Driver) deletionProtectionRequested(ctx context.Context, volumeParams map[string]string) (bool, error) {
+	if value, ok := volumeParams[AllowDeletionProtection]; !ok {
+		return false, nil
+	} else if allowed, err := strconv.ParseBool(value); err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "Invalid value %q of parameter %v: %v", value, AllowDeletionProtection, err)
+	} else if !allowed {
+		return false, nil
+	}
+	pvcName, pvcNamespace := volumeParams[PvcNameKey], volumeParams[PvcNamespace]
+	if d.kubeClient == nil || pvcName == "" || pvcNamespace == "" {
+		return false, nil
+	}
+	pvc, err := d.kubeClient.CoreV1().PersistentVolumeClaims(pvcNamespace).Get(ctx, pvcName, metav1.GetOptions{})
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "Could not get PVC %s/%s for annotation %v: %v", pvcNamespace, pvcName, DeletionProtectionAnnotation, err)
+	}
+	value, ok := pvc.Annotations[DeletionProtectionAnnotation]
+	if !ok {
+		return false, nil
+	}
+	protected, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, status.Errorf(codes.InvalidArgument, "Invalid value %q of PVC annotation %v: %v", value, DeletionProtectionAnnotation, err)
+	}
+	return protected, nil
+}
+
 func get64LenHash(text string) string {
 	h := sha256.New()
 	h.Write([]byte(text))
@@ -4,13 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/golang/mock/gomock"
@@ -896,6 +904,10 @@ func TestCreateVolume(t *testing.T) {
 					t.Fatalf("Volume Id mismatched. Expected: %v, Actual: %v", volumeId, res.Volume.VolumeId)
 				}
 
+				if res.Volume.VolumeContext[VolumeContextPvName] != pvName {
+					t.Fatalf("PV name in the volume context mismatched. Expected: %v, Actual: %v", pvName, res.Volume.VolumeContext[VolumeContextPvName])
+				}
+
 				mockCtl.Finish()
 			},
 		},
@@ -1379,7 +1391,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Volume name missing",
+			name: "Success: Tag access point with owner cluster",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1388,26 +1400,52 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+					clusterId:    "cluster-a",
 				}
 
 				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
+						Uid:              "1000",
+						Gid:              "1001",
 					},
 				}
 
 				ctx := context.Background()
+				fileSystem := &cloud.FileSystem{
+					FileSystemId: fsId,
+				}
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+				}
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(fileSystem, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Eq(volumeName), gomock.Any()).Return(accessPoint, nil).
+					Do(func(ctx context.Context, clientToken string, accessPointsOptions *cloud.AccessPointOptions) {
+						if owner := accessPointsOptions.Tags[OwnerClusterTagKey]; owner != "cluster-a" {
+							t.Fatalf("Owner cluster tag mismatched. Expected: %v, actual: %v", "cluster-a", owner)
+						}
+					})
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Capacity Range missing",
+			name: "Success: Tag access point of PVC annotated for deletion protection",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1416,39 +1454,83 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+					kubeClient: fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "pvc",
+							Namespace:   "team",
+							Annotations: map[string]string{DeletionProtectionAnnotation: "true"},
+						},
+					}),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						DirectoryPerms:   "777",
+						ProvisioningMode:        "efs-ap",
+						FsId:                    fsId,
+						DirectoryPerms:          "777",
+						Uid:                     "1000",
+						Gid:                     "1001",
+						PvcName:                 "pvc",
+						PvcNamespace:            "team",
+						AllowDeletionProtection: "true",
 					},
 				}
 
 				ctx := context.Background()
+				fileSystem := &cloud.FileSystem{
+					FileSystemId: fsId,
+				}
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+				}
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(fileSystem, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Eq(volumeName), gomock.Any()).Return(accessPoint, nil).
+					Do(func(ctx context.Context, clientToken string, accessPointsOptions *cloud.AccessPointOptions) {
+						if protection := accessPointsOptions.Tags[DeletionProtectionTagKey]; protection != "true" {
+							t.Fatalf("Deletion protection tag mismatched. Expected: %v, actual: %v", "true", protection)
+						}
+					})
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Volume capability Missing",
+			name: "Success: PVC is not looked up unless the StorageClass allows deletion protection",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
 
+				client := fake.NewSimpleClientset()
+				client.PrependReactor("get", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					t.Fatalf("Unexpected lookup of PVC")
+					return true, nil, nil
+				})
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+					kubeClient:   client,
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
@@ -1456,19 +1538,35 @@ func TestCreateVolume(t *testing.T) {
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
+						Uid:              "1000",
+						Gid:              "1001",
+						PvcName:          "pvc",
+						PvcNamespace:     "team",
 					},
 				}
 
 				ctx := context.Background()
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+				}
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(&cloud.FileSystem{FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Eq(volumeName), gomock.Any()).Return(accessPoint, nil).
+					Do(func(ctx context.Context, clientToken string, accessPointsOptions *cloud.AccessPointOptions) {
+						if protection, ok := accessPointsOptions.Tags[DeletionProtectionTagKey]; ok {
+							t.Fatalf("Unexpected deletion protection tag: %v", protection)
+						}
+					})
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Volume capability Not Supported",
+			name: "Fail: Invalid deletion protection annotation",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1477,40 +1575,48 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+					kubeClient: fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "pvc",
+							Namespace:   "team",
+							Annotations: map[string]string{DeletionProtectionAnnotation: "yes please"},
+						},
+					}),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
-					VolumeCapabilities: []*csi.VolumeCapability{
-						{
-							AccessType: &csi.VolumeCapability_Mount{
-								Mount: &csi.VolumeCapability_MountVolume{},
-							},
-							AccessMode: &csi.VolumeCapability_AccessMode{
-								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
-							},
-						},
-					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						DirectoryPerms:   "777",
+						ProvisioningMode:        "efs-ap",
+						FsId:                    fsId,
+						DirectoryPerms:          "777",
+						Uid:                     "1000",
+						Gid:                     "1001",
+						PvcName:                 "pvc",
+						PvcNamespace:            "team",
+						AllowDeletionProtection: "true",
 					},
 				}
 
 				ctx := context.Background()
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(&cloud.FileSystem{FileSystemId: fsId}, nil).AnyTimes()
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Expected InvalidArgument error, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Volume fsType not supported",
+			name: "Fail: Provisioning policy denies gid",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1520,21 +1626,15 @@ func TestCreateVolume(t *testing.T) {
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
 					tags:         parseTagsFromStr(""),
+					provisioningPolicy: &provisioningPolicy{Rules: []provisioningPolicyRule{
+						{FileSystemIds: []string{fsId}, GidRange: &idRange{Min: 1000, Max: 1999}},
+					}},
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
 					VolumeCapabilities: []*csi.VolumeCapability{
-						{
-							AccessType: &csi.VolumeCapability_Mount{
-								Mount: &csi.VolumeCapability_MountVolume{
-									FsType: "abc",
-								},
-							},
-							AccessMode: &csi.VolumeCapability_AccessMode{
-								Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
-							},
-						},
+						stdVolCap,
 					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
@@ -1542,23 +1642,25 @@ func TestCreateVolume(t *testing.T) {
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						AzName:           "us-east-1a",
+						PvcName:          "pvc",
+						PvcNamespace:     "team",
 					},
 				}
 
+				// The gid allocated from the default range is outside of the range allowed by the policy.
 				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(nil, nil)
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("Expected PermissionDenied error, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: AccessType is block",
+			name: "Fail: Provisioning policy denies identity of shared access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1567,40 +1669,52 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+					provisioningPolicy: &provisioningPolicy{Rules: []provisioningPolicyRule{
+						{FileSystemIds: []string{fsId}, GidRange: &idRange{Min: 1000, Max: 1999}},
+					}},
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
-					VolumeCapabilities: []*csi.VolumeCapability{
-						{
-							AccessType: &csi.VolumeCapability_Block{
-								Block: &csi.VolumeCapability_BlockVolume{},
-							},
-							AccessMode: &csi.VolumeCapability_AccessMode{
-								Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
-							},
-						},
-					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						DirectoryPerms:   "777",
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						Uid:                   "1000",
+						Gid:                   "1000",
+						SharedAccessPointName: "data",
+						PvcName:               "pvc",
+						PvcNamespace:          "team",
 					},
 				}
 
+				// The shared access point was created with a gid the policy does not allow, so the volume must not
+				// use it even though the requested gid is allowed.
 				ctx := context.Background()
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					PosixUser:     &cloud.PosixUser{Uid: 50000, Gid: 50000},
+					Tags:          map[string]string{SharedAccessPointNameTagKey: "data"},
+				}
+				mockCloud.EXPECT().FindAccessPointByClientToken(gomock.Eq(ctx), gomock.Eq(sharedAccessPointClientToken(fsId, "data")), gomock.Eq(fsId)).Return(accessPoint, nil)
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.PermissionDenied {
+					t.Fatalf("Expected PermissionDenied error, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Provisioning Mode Not Supported",
+			name: "Success: Create shared access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1609,33 +1723,61 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
 					VolumeCapabilities: []*csi.VolumeCapability{
 						stdVolCap,
 					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-fs",
-						FsId:             fsId,
-						DirectoryPerms:   "777",
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						BasePath:              "/shared",
+						SharedAccessPointName: "${.PVC.namespace}-data",
+						PvcName:               "pvc",
+						PvcNamespace:          "team",
 					},
 				}
 
 				ctx := context.Background()
-				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				clientToken := sharedAccessPointClientToken(fsId, "team-data")
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+				}
+				mockCloud.EXPECT().FindAccessPointByClientToken(gomock.Eq(ctx), gomock.Eq(clientToken), gomock.Eq(fsId)).Return(nil, nil)
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(nil, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Eq(clientToken), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, clientToken string, opts *cloud.AccessPointOptions) (*cloud.AccessPoint, error) {
+						if opts.DirectoryPath != "/shared/team-data" {
+							t.Fatalf("Expected directory /shared/team-data, got %v", opts.DirectoryPath)
+						}
+						if opts.Tags[SharedAccessPointNameTagKey] != "team-data" {
+							t.Fatalf("Expected shared access point name tag, got %v", opts.Tags)
+						}
+						return accessPoint, nil
+					})
+				mockCloud.EXPECT().TagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId),
+					gomock.Eq(map[string]string{sharedAccessPointConsumerTagKey(volumeName): "team/pvc"})).Return(nil)
+
+				res, err := driver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
+				}
+				if expected := volumeId + ":" + volumeName; res.Volume.VolumeId != expected {
+					t.Fatalf("Volume Id mismatched. Expected: %v, Actual: %v", expected, res.Volume.VolumeId)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Missing Provisioning Mode parameter",
+			name: "Success: Reuse shared access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1644,32 +1786,50 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
 					VolumeCapabilities: []*csi.VolumeCapability{
 						stdVolCap,
 					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
-						FsId:           fsId,
-						DirectoryPerms: "777",
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						SharedAccessPointName: "data",
 					},
 				}
 
 				ctx := context.Background()
-				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						SharedAccessPointNameTagKey:              "data",
+						sharedAccessPointConsumerTagKey("other"): "true",
+					},
+				}
+				mockCloud.EXPECT().FindAccessPointByClientToken(gomock.Eq(ctx), gomock.Eq(sharedAccessPointClientToken(fsId, "data")), gomock.Eq(fsId)).Return(accessPoint, nil)
+				mockCloud.EXPECT().TagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId),
+					gomock.Eq(map[string]string{sharedAccessPointConsumerTagKey(volumeName): "true"})).Return(nil)
+
+				res, err := driver.CreateVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
+				}
+				if expected := volumeId + ":" + volumeName; res.Volume.VolumeId != expected {
+					t.Fatalf("Volume Id mismatched. Expected: %v, Actual: %v", expected, res.Volume.VolumeId)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Missing Parameter FsId",
+			name: "Fail: Shared access point has too many tags",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1678,32 +1838,49 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
 					VolumeCapabilities: []*csi.VolumeCapability{
 						stdVolCap,
 					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						DirectoryPerms:   "777",
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						SharedAccessPointName: "data",
 					},
 				}
 
 				ctx := context.Background()
+				tags := map[string]string{}
+				for i := 0; i < maxAccessPointTags; i++ {
+					tags[sharedAccessPointConsumerTagKey(fmt.Sprintf("pvc-%d", i))] = "true"
+				}
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          tags,
+				}
+				mockCloud.EXPECT().FindAccessPointByClientToken(gomock.Eq(ctx), gomock.Any(), gomock.Eq(fsId)).Return(accessPoint, nil)
+
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.ResourceExhausted {
+					t.Fatalf("Expected ResourceExhausted, got %v", err)
+				}
+				if expected := fmt.Sprintf("has %d tags, the most EFS allows", maxAccessPointTags); !strings.Contains(err.Error(), expected) {
+					t.Fatalf("Expected error to contain %q, got %v", expected, err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: FsId cannot be blank",
+			name: "Success: Shared access point has room for one more tag",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1712,33 +1889,47 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
 					VolumeCapabilities: []*csi.VolumeCapability{
 						stdVolCap,
 					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             "     ",
-						DirectoryPerms:   "777",
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						SharedAccessPointName: "data",
 					},
 				}
 
 				ctx := context.Background()
-				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				tags := map[string]string{SharedAccessPointNameTagKey: "data"}
+				for i := 0; len(tags) < maxAccessPointTags-1; i++ {
+					tags[sharedAccessPointConsumerTagKey(fmt.Sprintf("pvc-%d", i))] = "true"
+				}
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          tags,
+				}
+				mockCloud.EXPECT().FindAccessPointByClientToken(gomock.Eq(ctx), gomock.Any(), gomock.Eq(fsId)).Return(accessPoint, nil)
+				mockCloud.EXPECT().TagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId),
+					gomock.Eq(map[string]string{sharedAccessPointConsumerTagKey(volumeName): "true"})).Return(nil)
+
+				if _, err := driver.CreateVolume(ctx, req); err != nil {
+					t.Fatalf("CreateVolume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Uid invalid",
+			name: "Fail: Shared access point with directory provisioning mode",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1751,17 +1942,45 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
 					VolumeCapabilities: []*csi.VolumeCapability{
 						stdVolCap,
 					},
-					Parameters: map[string]string{
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode:      DirectoryMode,
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						SharedAccessPointName: "data",
+					},
+				}
+
+				ctx := context.Background()
+				_, err := driver.CreateVolume(ctx, req)
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Expected InvalidArgument, got %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Volume name missing",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						Uid:              "invalid",
 					},
 				}
 
@@ -1774,7 +1993,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Uid cannot be negative",
+			name: "Fail: Capacity Range missing",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1787,17 +2006,10 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					CapacityRange: &csi.CapacityRange{
-						RequiredBytes: capacityRange,
-					},
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						Uid:              "-5",
 					},
 				}
 
@@ -1810,7 +2022,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Gid invalid",
+			name: "Fail: Volume capability Missing",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1826,14 +2038,10 @@ func TestCreateVolume(t *testing.T) {
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						Gid:              "invalid",
 					},
 				}
 
@@ -1846,7 +2054,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Gid cannot be negative",
+			name: "Fail: Volume capability Not Supported",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1863,13 +2071,19 @@ func TestCreateVolume(t *testing.T) {
 						RequiredBytes: capacityRange,
 					},
 					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
+						{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY,
+							},
+						},
 					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						Gid:              "-5",
 					},
 				}
 
@@ -1882,7 +2096,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Gid min cannot be 0",
+			name: "Fail: Volume fsType not supported",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1891,21 +2105,33 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
 				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						{
+							AccessType: &csi.VolumeCapability_Mount{
+								Mount: &csi.VolumeCapability_MountVolume{
+									FsType: "abc",
+								},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+							},
+						},
+					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						GidMin:           "0",
+						AzName:           "us-east-1a",
 					},
 				}
 
@@ -1918,7 +2144,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: GidMin must be an integer",
+			name: "Fail: AccessType is block",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1935,13 +2161,19 @@ func TestCreateVolume(t *testing.T) {
 						RequiredBytes: capacityRange,
 					},
 					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
+						{
+							AccessType: &csi.VolumeCapability_Block{
+								Block: &csi.VolumeCapability_BlockVolume{},
+							},
+							AccessMode: &csi.VolumeCapability_AccessMode{
+								Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+							},
+						},
 					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						GidMin:           "test",
 					},
 				}
 
@@ -1954,7 +2186,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: GidMax must be an integer",
+			name: "Fail: Provisioning Mode Not Supported",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -1974,11 +2206,9 @@ func TestCreateVolume(t *testing.T) {
 						stdVolCap,
 					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
+						ProvisioningMode: "efs-fs",
 						FsId:             fsId,
 						DirectoryPerms:   "777",
-						GidMin:           "2000",
-						GidMax:           "test",
 					},
 				}
 
@@ -1991,7 +2221,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: GidMax must be greater than GidMin",
+			name: "Fail: Directory provisioning mode without directoryPerms",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2011,24 +2241,21 @@ func TestCreateVolume(t *testing.T) {
 						stdVolCap,
 					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
+						ProvisioningMode: DirectoryMode,
 						FsId:             fsId,
-						DirectoryPerms:   "777",
-						GidMin:           "2000",
-						GidMax:           "1000",
 					},
 				}
 
 				ctx := context.Background()
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Expected InvalidArgument, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: GidMax must be provided with GidMin",
+			name: "Fail: Directory provisioning mode with reuseAccessPoint",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2048,30 +2275,33 @@ func TestCreateVolume(t *testing.T) {
 						stdVolCap,
 					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						DirectoryPerms:   "777",
-						GidMin:           "2000",
+						ProvisioningMode:    DirectoryMode,
+						FsId:                fsId,
+						DirectoryPerms:      "777",
+						ReuseAccessPointKey: "true",
+						PvcNameKey:          "pvc",
 					},
 				}
 
 				ctx := context.Background()
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Expected InvalidArgument, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: GidMin must be provided with GidMax",
+			name: "Fail: Directory provisioning mode cannot mount file system",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
+					mounter:      mockMounter,
 					gidAllocator: NewGidAllocator(),
 				}
 
@@ -2084,23 +2314,26 @@ func TestCreateVolume(t *testing.T) {
 						stdVolCap,
 					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
+						ProvisioningMode: DirectoryMode,
 						FsId:             fsId,
-						DirectoryPerms:   "777",
-						GidMax:           "2000",
+						DirectoryPerms:   "750",
+						BasePath:         "/dynamic",
 					},
 				}
 
 				ctx := context.Background()
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Eq(fsId)).Return(&cloud.FileSystem{FileSystemId: fsId}, nil)
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(fsId), gomock.Any(), gomock.Eq("efs"), gomock.Any()).Return(errors.New("Failed to mount"))
 				_, err := driver.CreateVolume(ctx, req)
-				if err == nil {
-					t.Fatal("CreateVolume did not fail")
+				if status.Code(err) != codes.Internal {
+					t.Fatalf("Expected Internal, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: File system does not exist with fixed uid/gid",
+			name: "Fail: Missing Provisioning Mode parameter",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2113,25 +2346,19 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
-						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
-						DirectoryPerms:   "777",
-						Uid:              "1000",
-						Gid:              "1001",
+						FsId:           fsId,
+						DirectoryPerms: "777",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrNotFound)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2140,7 +2367,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: File system does not exist",
+			name: "Fail: Missing Parameter FsId",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2153,23 +2380,19 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrNotFound)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2178,7 +2401,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: DescribeFileSystem Access Denied with fixed uid/gid",
+			name: "Fail: FsId cannot be blank",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2191,25 +2414,20 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
-						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
+						FsId:             "     ",
 						DirectoryPerms:   "777",
-						Uid:              "1000",
-						Gid:              "1001",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2218,7 +2436,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: DescribeFileSystem Access Denied",
+			name: "Fail: Uid invalid",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2231,23 +2449,21 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
+						Uid:              "invalid",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2256,7 +2472,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Describe File system call fails with fixed uid/gid",
+			name: "Fail: Uid cannot be negative",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2269,25 +2485,21 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						Uid:              "1000",
-						Gid:              "1001",
+						Uid:              "-5",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeFileSystem failed"))
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2296,7 +2508,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: List access points call fails",
+			name: "Fail: Gid invalid",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2309,23 +2521,21 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
+						Gid:              "invalid",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("ListAccessPoints failed"))
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2334,7 +2544,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Create Access Point call fails",
+			name: "Fail: Gid cannot be negative",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2347,24 +2557,21 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
+						Gid:              "-5",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{}, nil)
-				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(nil, errors.New("CreateAccessPoint call failed"))
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2373,7 +2580,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: CreateAccessPoint Access Denied",
+			name: "Fail: Gid min cannot be 0",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2386,24 +2593,21 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "2000",
 						DirectoryPerms:   "777",
+						GidMin:           "0",
 					},
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{}, nil)
-				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
@@ -2412,7 +2616,7 @@ func TestCreateVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: Run out of GIDs",
+			name: "Fail: GidMin must be an integer",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2425,53 +2629,104 @@ func TestCreateVolume(t *testing.T) {
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
-					VolumeCapabilities: []*csi.VolumeCapability{
-						stdVolCap,
-					},
 					CapacityRange: &csi.CapacityRange{
 						RequiredBytes: capacityRange,
 					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
-						GidMin:           "1000",
-						GidMax:           "1001",
 						DirectoryPerms:   "777",
+						GidMin:           "test",
 					},
 				}
 
 				ctx := context.Background()
-				ap1 := &cloud.AccessPoint{
-					AccessPointId: apId,
-					FileSystemId:  fsId,
-					PosixUser: &cloud.PosixUser{
-						Gid: 1000,
-						Uid: 1000,
-					},
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
 				}
-				ap2 := &cloud.AccessPoint{
-					AccessPointId: apId,
-					FileSystemId:  fsId,
-					PosixUser: &cloud.PosixUser{
-						Gid: 1001,
-						Uid: 1001,
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: GidMax must be an integer",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						GidMin:           "2000",
+						GidMax:           "test",
 					},
 				}
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{ap1, ap2}, nil).AnyTimes()
-				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(ap2, nil).AnyTimes()
 
-				var err error
-				// All GIDs from available range are taken, CreateVolume should fail.
-				_, err = driver.CreateVolume(ctx, req)
+				ctx := context.Background()
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: GidMax must be greater than GidMin",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						GidMin:           "2000",
+						GidMax:           "1000",
+					},
+				}
 
+				ctx := context.Background()
+				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
-					t.Fatalf("CreateVolume did not fail")
+					t.Fatal("CreateVolume did not fail")
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Cannot assume role for x-account",
+			name: "Fail: GidMax must be provided with GidMin",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2480,12 +2735,79 @@ func TestCreateVolume(t *testing.T) {
 					endpoint:     endpoint,
 					cloud:        mockCloud,
 					gidAllocator: NewGidAllocator(),
-					tags:         parseTagsFromStr(""),
 				}
 
-				secrets := map[string]string{}
-				secrets["awsRoleArn"] = "arn:aws:iam::1234567890:role/EFSCrossAccountRole"
-				secrets["crossaccount"] = "true"
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						GidMin:           "2000",
+					},
+				}
+
+				ctx := context.Background()
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: GidMin must be provided with GidMax",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						GidMax:           "2000",
+					},
+				}
+
+				ctx := context.Background()
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: File system does not exist with fixed uid/gid",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
 
 				req := &csi.CreateVolumeRequest{
 					Name: volumeName,
@@ -2501,30 +2823,26 @@ func TestCreateVolume(t *testing.T) {
 						GidMin:           "1000",
 						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						AzName:           "us-east-1a",
+						Uid:              "1000",
+						Gid:              "1001",
 					},
-					Secrets: secrets,
 				}
 
 				ctx := context.Background()
-
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrNotFound)
 				_, err := driver.CreateVolume(ctx, req)
-
 				if err == nil {
-					t.Fatalf("CreateVolume did not fail")
+					t.Fatal("CreateVolume did not fail")
 				}
-
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: subPathPattern is specified but uses unsupported attributes",
+			name: "Fail: File system does not exist",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
 
-				subPathPattern := "${.PVC.name}/${foo}"
-
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
@@ -2542,33 +2860,27 @@ func TestCreateVolume(t *testing.T) {
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						SubPathPattern:   subPathPattern,
 					},
 				}
 
 				ctx := context.Background()
-
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
-
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrNotFound)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
 				}
-				if status.Code(err) != codes.InvalidArgument {
-					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
-				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: resulting accessPointDirectory is too over 100 characters",
+			name: "Fail: DescribeFileSystem Access Denied with fixed uid/gid",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
 
-				subPathPattern := "this-directory-name-is-far-too-long-for-any-practical-purposes-and-only-serves-to-prove-a-point"
-
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
@@ -2586,33 +2898,29 @@ func TestCreateVolume(t *testing.T) {
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						SubPathPattern:   subPathPattern,
+						Uid:              "1000",
+						Gid:              "1001",
 					},
 				}
 
 				ctx := context.Background()
-
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
-
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
 				}
-				if status.Code(err) != codes.InvalidArgument {
-					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
-				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail:  resulting accessPointDirectory contains over 4 subdirectories",
+			name: "Fail: DescribeFileSystem Access Denied",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
 
-				subPathPattern := "a/b/c/d/e/f"
-
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
@@ -2630,46 +2938,984 @@ func TestCreateVolume(t *testing.T) {
 					Parameters: map[string]string{
 						ProvisioningMode: "efs-ap",
 						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
 						DirectoryPerms:   "777",
-						SubPathPattern:   subPathPattern,
 					},
 				}
 
 				ctx := context.Background()
-
-				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
-
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
 				_, err := driver.CreateVolume(ctx, req)
 				if err == nil {
 					t.Fatal("CreateVolume did not fail")
 				}
-				if status.Code(err) != codes.InvalidArgument {
-					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
-				}
 				mockCtl.Finish()
 			},
 		},
-	}
+		{
+			name: "Fail: Describe File system call fails with fixed uid/gid",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
 
-	for _, tc := range testCases {
-		t.Run(tc.name, tc.testFunc)
-	}
-}
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
 
-func TestDeleteVolume(t *testing.T) {
-	var (
-		apId     = "fsap-abcd1234xyz987"
-		fsId     = "fs-abcd1234"
-		endpoint = "endpoint"
-		volumeId = "fs-abcd1234::fsap-abcd1234xyz987"
-	)
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+						Uid:              "1000",
+						Gid:              "1001",
+					},
+				}
 
-	testCases := []struct {
-		name     string
-		testFunc func(t *testing.T)
-	}{
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeFileSystem(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("DescribeFileSystem failed"))
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: List access points call fails",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+					},
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, errors.New("ListAccessPoints failed"))
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Create Access Point call fails",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+					},
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{}, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(nil, errors.New("CreateAccessPoint call failed"))
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: CreateAccessPoint Access Denied",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+					},
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{}, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(nil, cloud.ErrAccessDenied)
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: CreateAccessPoint limit exceeded records event on PVC",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				eventRecorder, recorder := newFakeVolumeEventRecorder(&corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"},
+				})
+
+				driver := &Driver{
+					endpoint:      endpoint,
+					cloud:         mockCloud,
+					gidAllocator:  NewGidAllocator(),
+					eventRecorder: eventRecorder,
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+						PvcName:          "pvc",
+						PvcNamespace:     "default",
+					},
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{}, nil)
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(nil, cloud.ErrAccessPointLimitExceeded)
+				_, err := driver.CreateVolume(ctx, req)
+				if status.Code(err) != codes.ResourceExhausted {
+					t.Fatalf("Expected ResourceExhausted error, got %v", err)
+				}
+				expectEvent(t, recorder, ReasonAccessPointLimitReached)
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Run out of GIDs",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "1001",
+						DirectoryPerms:   "777",
+					},
+				}
+
+				ctx := context.Background()
+				ap1 := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					PosixUser: &cloud.PosixUser{
+						Gid: 1000,
+						Uid: 1000,
+					},
+				}
+				ap2 := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					PosixUser: &cloud.PosixUser{
+						Gid: 1001,
+						Uid: 1001,
+					},
+				}
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return([]*cloud.AccessPoint{ap1, ap2}, nil).AnyTimes()
+				mockCloud.EXPECT().CreateAccessPoint(gomock.Eq(ctx), gomock.Any(), gomock.Any()).Return(ap2, nil).AnyTimes()
+
+				var err error
+				// All GIDs from available range are taken, CreateVolume should fail.
+				_, err = driver.CreateVolume(ctx, req)
+
+				if err == nil {
+					t.Fatalf("CreateVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Cannot assume role for x-account",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+					tags:         parseTagsFromStr(""),
+				}
+
+				secrets := map[string]string{}
+				secrets["awsRoleArn"] = "arn:aws:iam::1234567890:role/EFSCrossAccountRole"
+				secrets["crossaccount"] = "true"
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						GidMin:           "1000",
+						GidMax:           "2000",
+						DirectoryPerms:   "777",
+						AzName:           "us-east-1a",
+					},
+					Secrets: secrets,
+				}
+
+				ctx := context.Background()
+
+				_, err := driver.CreateVolume(ctx, req)
+
+				if err == nil {
+					t.Fatalf("CreateVolume did not fail")
+				}
+
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: subPathPattern is specified but uses unsupported attributes",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				subPathPattern := "${.PVC.name}/${foo}"
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						SubPathPattern:   subPathPattern,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
+
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: subPathPattern leads out of the base path",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+					provisioningPolicy: &provisioningPolicy{Rules: []provisioningPolicyRule{
+						{BasePaths: []string{"/team-a"}},
+					}},
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode:      "efs-ap",
+						FsId:                  fsId,
+						DirectoryPerms:        "777",
+						BasePath:              "/team-a",
+						SubPathPattern:        "../team-b/${.PVC.name}",
+						EnsureUniqueDirectory: "false",
+						PvcName:               "pvc",
+						PvcNamespace:          "team-a",
+					},
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
+
+				_, err := driver.CreateVolume(ctx, req)
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Expected InvalidArgument error, got %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: resulting accessPointDirectory is too over 100 characters",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				subPathPattern := "this-directory-name-is-far-too-long-for-any-practical-purposes-and-only-serves-to-prove-a-point"
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						SubPathPattern:   subPathPattern,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
+
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail:  resulting accessPointDirectory contains over 4 subdirectories",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				subPathPattern := "a/b/c/d/e/f"
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.CreateVolumeRequest{
+					Name: volumeName,
+					VolumeCapabilities: []*csi.VolumeCapability{
+						stdVolCap,
+					},
+					CapacityRange: &csi.CapacityRange{
+						RequiredBytes: capacityRange,
+					},
+					Parameters: map[string]string{
+						ProvisioningMode: "efs-ap",
+						FsId:             fsId,
+						DirectoryPerms:   "777",
+						SubPathPattern:   subPathPattern,
+					},
+				}
+
+				ctx := context.Background()
+
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Any()).Return(nil, nil)
+
+				_, err := driver.CreateVolume(ctx, req)
+				if err == nil {
+					t.Fatal("CreateVolume did not fail")
+				}
+				if status.Code(err) != codes.InvalidArgument {
+					t.Fatalf("Did not throw InvalidArgument error, instead threw %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, tc.testFunc)
+	}
+}
+
+func TestDeleteVolume(t *testing.T) {
+	var (
+		apId     = "fsap-abcd1234xyz987"
+		fsId     = "fs-abcd1234"
+		endpoint = "endpoint"
+		volumeId = "fs-abcd1234::fsap-abcd1234xyz987"
+	)
+
+	testCases := []struct {
+		name     string
+		testFunc func(t *testing.T)
+	}{
+		{
+			name: "Success: Normal flow",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(&cloud.AccessPoint{AccessPointId: apId, FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Normal flow with deleteAccessPointRootDir",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId:      apId,
+					FileSystemId:       fsId,
+					AccessPointRootDir: "",
+					CapacityGiB:        0,
+				}
+
+				ctx := context.Background()
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Unmount(gomock.Any()).Return(nil)
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Normal flow with DeleteAccessPointRootDir feature gate",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+				defer setFeatureGatesDuringTest(t, map[string]bool{string(DeleteAccessPointRootDir): true})()
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					mounter:      mockMounter,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+				}
+
+				ctx := context.Background()
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Unmount(gomock.Any()).Return(nil)
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: DescribeAccessPoint Access Point Does not exist",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, cloud.ErrNotFound)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: DescribeAccessPoint Access Denied",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, cloud.ErrAccessDenied)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatalf("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: DescribeAccessPoint failed",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, errors.New("Describe Access Point failed"))
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatalf("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Fail to make directory for access point mount",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId:      apId,
+					FileSystemId:       fsId,
+					AccessPointRootDir: "",
+					CapacityGiB:        0,
+				}
+
+				ctx := context.Background()
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(errors.New("Failed to makeDir"))
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatal("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Fail to mount file system on directory for access point root directory removal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId:      apId,
+					FileSystemId:       fsId,
+					AccessPointRootDir: "",
+					CapacityGiB:        0,
+				}
+
+				ctx := context.Background()
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("Failed to mount"))
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatal("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: Fail to unmount file system after access point root directory removal",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
+
+				driver := &Driver{
+					endpoint:                 endpoint,
+					cloud:                    mockCloud,
+					mounter:                  mockMounter,
+					gidAllocator:             NewGidAllocator(),
+					deleteAccessPointRootDir: true,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId:      apId,
+					FileSystemId:       fsId,
+					AccessPointRootDir: "",
+					CapacityGiB:        0,
+				}
+
+				ctx := context.Background()
+				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Unmount(gomock.Any()).Return(errors.New("Failed to unmount"))
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatal("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Access Point already deleted",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(&cloud.AccessPoint{AccessPointId: apId, FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(cloud.ErrNotFound)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: DeleteAccessPoint access denied",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(&cloud.AccessPoint{AccessPointId: apId, FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(cloud.ErrAccessDenied)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatal("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: DeleteAccessPoint access denied records event on PV",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+				eventRecorder, recorder := newFakeVolumeEventRecorder(&corev1.PersistentVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+					Spec: corev1.PersistentVolumeSpec{
+						PersistentVolumeSource: corev1.PersistentVolumeSource{
+							CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: volumeId},
+						},
+					},
+				})
+
+				driver := &Driver{
+					endpoint:      endpoint,
+					cloud:         mockCloud,
+					gidAllocator:  NewGidAllocator(),
+					eventRecorder: eventRecorder,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(&cloud.AccessPoint{AccessPointId: apId, FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(cloud.ErrAccessDenied)
+				_, err := driver.DeleteVolume(ctx, req)
+				if status.Code(err) != codes.Unauthenticated {
+					t.Fatalf("Expected Unauthenticated error, got %v", err)
+				}
+				expectEvent(t, recorder, ReasonAccessDenied)
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Fail: DeleteVolume fails",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId,
+				}
+
+				ctx := context.Background()
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(&cloud.AccessPoint{AccessPointId: apId, FileSystemId: fsId}, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(errors.New("Delete Volume failed"))
+				_, err := driver.DeleteVolume(ctx, req)
+				if err == nil {
+					t.Fatal("DeleteVolume did not fail")
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Keep shared access point used by other volumes",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:     endpoint,
+					cloud:        mockCloud,
+					gidAllocator: NewGidAllocator(),
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId + ":pv-1",
+				}
+
+				ctx := context.Background()
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+						sharedAccessPointConsumerTagKey("pv-2"): "true",
+					},
+				}
+				remaining := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-2"): "true",
+					},
+				}
+				gomock.InOrder(
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2),
+					mockCloud.EXPECT().UntagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId), gomock.Eq([]string{sharedAccessPointConsumerTagKey("pv-1")})).Return(nil),
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(remaining, nil),
+				)
+				_, err := driver.DeleteVolume(ctx, req)
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
 		{
-			name: "Success: Normal flow",
+			name: "Success: Delete shared access point with its last volume",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2681,11 +3927,31 @@ func TestDeleteVolume(t *testing.T) {
 				}
 
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: volumeId + ":pv-1",
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+					},
+				}
+				remaining := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						SharedAccessPointNameTagKey: "data",
+					},
+				}
+				gomock.InOrder(
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2),
+					mockCloud.EXPECT().UntagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId), gomock.Eq([]string{sharedAccessPointConsumerTagKey("pv-1")})).Return(nil),
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(remaining, nil),
+					mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil),
+				)
 				_, err := driver.DeleteVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Delete Volume failed: %v", err)
@@ -2694,37 +3960,48 @@ func TestDeleteVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Success: Normal flow with deleteAccessPointRootDir",
+			name: "Success: Remove volume from shared access point owned by another cluster",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                   endpoint,
+					cloud:                      mockCloud,
+					gidAllocator:               NewGidAllocator(),
+					clusterId:                  "cluster-a",
+					foreignAccessPointDeletion: AccessPointDeletionDetach,
 				}
 
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: volumeId + ":pv-1",
 				}
 
+				ctx := context.Background()
 				accessPoint := &cloud.AccessPoint{
-					AccessPointId:      apId,
-					FileSystemId:       fsId,
-					AccessPointRootDir: "",
-					CapacityGiB:        0,
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						OwnerClusterTagKey:                      "cluster-b",
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+					},
 				}
-
-				ctx := context.Background()
-				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
-				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-				mockMounter.EXPECT().Unmount(gomock.Any()).Return(nil)
-				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
-				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
+				remaining := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						OwnerClusterTagKey:          "cluster-b",
+						SharedAccessPointNameTagKey: "data",
+					},
+				}
+				// The consumer tag of the volume is removed, so that the owning cluster can delete the access point,
+				// but the access point is not deleted.
+				gomock.InOrder(
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2),
+					mockCloud.EXPECT().UntagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId), gomock.Eq([]string{sharedAccessPointConsumerTagKey("pv-1")})).Return(nil),
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(remaining, nil),
+				)
 				_, err := driver.DeleteVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Delete Volume failed: %v", err)
@@ -2733,26 +4010,68 @@ func TestDeleteVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Success: DescribeAccessPoint Access Point Does not exist",
+			name: "Fail: Refuse to remove volume from shared access point owned by another cluster",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                   endpoint,
+					cloud:                      mockCloud,
+					gidAllocator:               NewGidAllocator(),
+					clusterId:                  "cluster-a",
+					foreignAccessPointDeletion: AccessPointDeletionRefuse,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId + ":pv-1",
+				}
+
+				ctx := context.Background()
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						OwnerClusterTagKey:                      "cluster-b",
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+					},
+				}
+				// The consumer tag is kept, so that the retries of DeleteVolume are refused the same way.
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2)
+				_, err := driver.DeleteVolume(ctx, req)
+				if status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("Expected FailedPrecondition error, got %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Detach access point owned by another cluster",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:                   endpoint,
+					cloud:                      mockCloud,
+					gidAllocator:               NewGidAllocator(),
+					clusterId:                  "cluster-a",
+					foreignAccessPointDeletion: AccessPointDeletionDetach,
 				}
 
 				req := &csi.DeleteVolumeRequest{
 					VolumeId: volumeId,
 				}
 
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          map[string]string{OwnerClusterTagKey: "cluster-b"},
+				}
+
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, cloud.ErrNotFound)
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
 				_, err := driver.DeleteVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Delete Volume failed: %v", err)
@@ -2761,74 +4080,102 @@ func TestDeleteVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: DescribeAccessPoint Access Denied",
+			name: "Fail: Refuse to delete access point owned by another cluster",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
+				eventRecorder, recorder := newFakeVolumeEventRecorder(&corev1.PersistentVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+					Spec: corev1.PersistentVolumeSpec{
+						PersistentVolumeSource: corev1.PersistentVolumeSource{
+							CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: volumeId},
+						},
+					},
+				})
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                   endpoint,
+					cloud:                      mockCloud,
+					gidAllocator:               NewGidAllocator(),
+					eventRecorder:              eventRecorder,
+					clusterId:                  "cluster-a",
+					foreignAccessPointDeletion: AccessPointDeletionRefuse,
 				}
 
 				req := &csi.DeleteVolumeRequest{
 					VolumeId: volumeId,
 				}
 
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          map[string]string{OwnerClusterTagKey: "cluster-b"},
+				}
+
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, cloud.ErrAccessDenied)
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatalf("DeleteVolume did not fail")
+				if status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("Expected FailedPrecondition error, got %v", err)
 				}
+				expectEvent(t, recorder, ReasonAccessPointOwnedByOtherCluster)
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: DescribeAccessPoint failed",
+			name: "Success: Delete access point owned by this cluster",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                   endpoint,
+					cloud:                      mockCloud,
+					gidAllocator:               NewGidAllocator(),
+					clusterId:                  "cluster-a",
+					foreignAccessPointDeletion: AccessPointDeletionRefuse,
 				}
 
 				req := &csi.DeleteVolumeRequest{
 					VolumeId: volumeId,
 				}
 
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          map[string]string{OwnerClusterTagKey: "cluster-a"},
+				}
+
 				ctx := context.Background()
-				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil, errors.New("Describe Access Point failed"))
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(nil)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatalf("DeleteVolume did not fail")
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Fail to make directory for access point mount",
+			name: "Fail: Refuse to delete protected access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
+				eventRecorder, recorder := newFakeVolumeEventRecorder(&corev1.PersistentVolume{
+					ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+					Spec: corev1.PersistentVolumeSpec{
+						PersistentVolumeSource: corev1.PersistentVolumeSource{
+							CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: volumeId},
+						},
+					},
+				})
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                     endpoint,
+					cloud:                        mockCloud,
+					gidAllocator:                 NewGidAllocator(),
+					eventRecorder:                eventRecorder,
+					protectedAccessPointDeletion: AccessPointDeletionRefuse,
 				}
 
 				req := &csi.DeleteVolumeRequest{
@@ -2836,35 +4183,35 @@ func TestDeleteVolume(t *testing.T) {
 				}
 
 				accessPoint := &cloud.AccessPoint{
-					AccessPointId:      apId,
-					FileSystemId:       fsId,
-					AccessPointRootDir: "",
-					CapacityGiB:        0,
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          map[string]string{DeletionProtectionTagKey: "true"},
 				}
 
 				ctx := context.Background()
-				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(errors.New("Failed to makeDir"))
 				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatal("DeleteVolume did not fail")
+				if status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("Expected FailedPrecondition error, got %v", err)
 				}
+				expectEvent(t, recorder, ReasonAccessPointDeletionProtected)
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Fail to mount file system on directory for access point root directory removal",
+			name: "Success: Detach protected access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
 				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                     endpoint,
+					cloud:                        mockCloud,
+					mounter:                      mockMounter,
+					gidAllocator:                 NewGidAllocator(),
+					deleteAccessPointRootDir:     true,
+					protectedAccessPointDeletion: AccessPointDeletionDetach,
 				}
 
 				req := &csi.DeleteVolumeRequest{
@@ -2872,79 +4219,132 @@ func TestDeleteVolume(t *testing.T) {
 				}
 
 				accessPoint := &cloud.AccessPoint{
-					AccessPointId:      apId,
-					FileSystemId:       fsId,
-					AccessPointRootDir: "",
-					CapacityGiB:        0,
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags:          map[string]string{DeletionProtectionTagKey: "true"},
 				}
 
+				// Neither the access point nor its root directory are deleted.
 				ctx := context.Background()
-				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
-				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("Failed to mount"))
 				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatal("DeleteVolume did not fail")
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: Fail to unmount file system after access point root directory removal",
+			name: "Fail: Refuse to delete last volume of protected shared access point",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
-				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
-					endpoint:                 endpoint,
-					cloud:                    mockCloud,
-					mounter:                  mockMounter,
-					gidAllocator:             NewGidAllocator(),
-					deleteAccessPointRootDir: true,
+					endpoint:                     endpoint,
+					cloud:                        mockCloud,
+					gidAllocator:                 NewGidAllocator(),
+					protectedAccessPointDeletion: AccessPointDeletionRefuse,
 				}
 
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: volumeId + ":pv-1",
 				}
 
+				ctx := context.Background()
 				accessPoint := &cloud.AccessPoint{
-					AccessPointId:      apId,
-					FileSystemId:       fsId,
-					AccessPointRootDir: "",
-					CapacityGiB:        0,
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						DeletionProtectionTagKey:                "true",
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+					},
+				}
+				// The consumer tag is kept, so that the retries of DeleteVolume are refused the same way.
+				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2)
+				_, err := driver.DeleteVolume(ctx, req)
+				if status.Code(err) != codes.FailedPrecondition {
+					t.Fatalf("Expected FailedPrecondition error, got %v", err)
+				}
+				mockCtl.Finish()
+			},
+		},
+		{
+			name: "Success: Remove volume from protected shared access point used by other volumes",
+			testFunc: func(t *testing.T) {
+				mockCtl := gomock.NewController(t)
+				mockCloud := mocks.NewMockCloud(mockCtl)
+
+				driver := &Driver{
+					endpoint:                     endpoint,
+					cloud:                        mockCloud,
+					gidAllocator:                 NewGidAllocator(),
+					protectedAccessPointDeletion: AccessPointDeletionRefuse,
+				}
+
+				req := &csi.DeleteVolumeRequest{
+					VolumeId: volumeId + ":pv-1",
 				}
 
 				ctx := context.Background()
-				mockMounter.EXPECT().MakeDir(gomock.Any()).Return(nil)
-				mockMounter.EXPECT().Mount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-				mockMounter.EXPECT().Unmount(gomock.Any()).Return(errors.New("Failed to unmount"))
-				mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil)
+				accessPoint := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						DeletionProtectionTagKey:                "true",
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-1"): "true",
+						sharedAccessPointConsumerTagKey("pv-2"): "true",
+					},
+				}
+				remaining := &cloud.AccessPoint{
+					AccessPointId: apId,
+					FileSystemId:  fsId,
+					Tags: map[string]string{
+						DeletionProtectionTagKey:                "true",
+						SharedAccessPointNameTagKey:             "data",
+						sharedAccessPointConsumerTagKey("pv-2"): "true",
+					},
+				}
+				// The access point is not deleted with this volume, so its protection does not matter.
+				gomock.InOrder(
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(accessPoint, nil).Times(2),
+					mockCloud.EXPECT().UntagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId), gomock.Eq([]string{sharedAccessPointConsumerTagKey("pv-1")})).Return(nil),
+					mockCloud.EXPECT().DescribeAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(remaining, nil),
+				)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatal("DeleteVolume did not fail")
+				if err != nil {
+					t.Fatalf("Delete Volume failed: %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Success: Access Point already deleted",
+			name: "Success: Directory volume",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
+					mounter:      mockMounter,
 					gidAllocator: NewGidAllocator(),
 				}
 
+				volId := provisionedDirectoryVolumeId(fsId, "/dynamic/pvc-123")
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: volId,
 				}
 
+				// The file system is mounted at a target unique to the volume.
+				target := TempMountPathPrefix + "/" + get64LenHash(volId)
 				ctx := context.Background()
-				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(cloud.ErrNotFound)
+				mockMounter.EXPECT().MakeDir(gomock.Eq(target)).Return(nil)
+				mockMounter.EXPECT().Mount(gomock.Eq(fsId), gomock.Eq(target), gomock.Eq("efs"), gomock.Any()).Return(nil)
+				mockMounter.EXPECT().Unmount(gomock.Eq(target)).Return(nil)
 				_, err := driver.DeleteVolume(ctx, req)
 				if err != nil {
 					t.Fatalf("Delete Volume failed: %v", err)
@@ -2953,32 +4353,34 @@ func TestDeleteVolume(t *testing.T) {
 			},
 		},
 		{
-			name: "Fail: DeleteAccessPoint access denied",
+			name: "Fail: Directory of static volume is not deleted",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
+				mockMounter := mocks.NewMockMounter(mockCtl)
 
 				driver := &Driver{
 					endpoint:     endpoint,
 					cloud:        mockCloud,
+					mounter:      mockMounter,
 					gidAllocator: NewGidAllocator(),
 				}
 
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: fsId + ":/dynamic/pvc-123",
 				}
 
+				// The file system is not mounted to delete the directory.
 				ctx := context.Background()
-				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(cloud.ErrAccessDenied)
 				_, err := driver.DeleteVolume(ctx, req)
-				if err == nil {
-					t.Fatal("DeleteVolume did not fail")
+				if status.Code(err) != codes.NotFound {
+					t.Fatalf("Expected NotFound, got %v", err)
 				}
 				mockCtl.Finish()
 			},
 		},
 		{
-			name: "Fail: DeleteVolume fails",
+			name: "Fail: Directory volume is the root of the file system",
 			testFunc: func(t *testing.T) {
 				mockCtl := gomock.NewController(t)
 				mockCloud := mocks.NewMockCloud(mockCtl)
@@ -2990,11 +4392,10 @@ func TestDeleteVolume(t *testing.T) {
 				}
 
 				req := &csi.DeleteVolumeRequest{
-					VolumeId: volumeId,
+					VolumeId: provisionedDirectoryVolumeId(fsId, "/"),
 				}
 
 				ctx := context.Background()
-				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(apId)).Return(errors.New("Delete Volume failed"))
 				_, err := driver.DeleteVolume(ctx, req)
 				if err == nil {
 					t.Fatal("DeleteVolume did not fail")
@@ -3205,6 +4606,173 @@ func TestValidateVolumeCapabilities(t *testing.T) {
 	}
 }
 
+func TestGetReuseClientToken(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "data",
+			Namespace:   "team",
+			Annotations: map[string]string{"example.com/volume-id": "volume-1"},
+		},
+	}
+	testCases := []struct {
+		name          string
+		identity      string
+		clusterId     string
+		pvcName       string
+		pvcNamespace  string
+		expectedToken string
+		expectedCode  codes.Code
+	}{
+		{
+			name:          "default identity is the PVC name",
+			pvcName:       "data",
+			expectedToken: get64LenHash("data"),
+		},
+		{
+			name:          "namespaced name",
+			identity:      ReuseIdentityNamespacedName,
+			pvcName:       "data",
+			pvcNamespace:  "team",
+			expectedToken: get64LenHash("team/data"),
+		},
+		{
+			name:          "cluster and namespaced name",
+			identity:      ReuseIdentityClusterNamespacedName,
+			clusterId:     "cluster-a",
+			pvcName:       "data",
+			pvcNamespace:  "team",
+			expectedToken: get64LenHash("cluster-a/team/data"),
+		},
+		{
+			name:          "annotation",
+			identity:      ReuseIdentityAnnotationPrefix + "example.com/volume-id",
+			pvcName:       "data",
+			pvcNamespace:  "team",
+			expectedToken: get64LenHash("volume-1"),
+		},
+		{
+			name:         "fail: missing PVC name",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "fail: missing PVC namespace",
+			identity:     ReuseIdentityNamespacedName,
+			pvcName:      "data",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "fail: missing cluster ID",
+			identity:     ReuseIdentityClusterNamespacedName,
+			pvcName:      "data",
+			pvcNamespace: "team",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "fail: missing annotation",
+			identity:     ReuseIdentityAnnotationPrefix + "example.com/other",
+			pvcName:      "data",
+			pvcNamespace: "team",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "fail: invalid identity",
+			identity:     "uid",
+			pvcName:      "data",
+			pvcNamespace: "team",
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driver := &Driver{
+				kubeClient: fake.NewSimpleClientset(pvc),
+				clusterId:  tc.clusterId,
+			}
+			volumeParams := map[string]string{
+				ReuseAccessPointKey: "true",
+				PvcNameKey:          tc.pvcName,
+				PvcNamespace:        tc.pvcNamespace,
+			}
+			if tc.identity != "" {
+				volumeParams[ReuseIdentityKey] = tc.identity
+			}
+
+			token, err := driver.getReuseClientToken(context.Background(), volumeParams)
+			if status.Code(err) != tc.expectedCode {
+				t.Fatalf("Expected code %v, got %v", tc.expectedCode, err)
+			}
+			if token != tc.expectedToken {
+				t.Fatalf("Expected token %v, got %v", tc.expectedToken, token)
+			}
+		})
+	}
+}
+
+func TestMakeVolumeDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := "/dynamic/pvc-123"
+
+	// Creating an existing directory succeeds, so that CreateVolume can be retried.
+	for i := 0; i < 2; i++ {
+		if err := makeVolumeDirectory(root, dir, 0750, -1, -1); err != nil {
+			t.Fatalf("makeVolumeDirectory failed: %v", err)
+		}
+	}
+	info, err := os.Stat(root + dir)
+	if err != nil {
+		t.Fatalf("Directory was not created: %v", err)
+	}
+	if perms := info.Mode().Perm(); perms != 0750 {
+		t.Fatalf("Expected permissions 0750, got %o", perms)
+	}
+}
+
+func TestRemoveVolumeDirectory(t *testing.T) {
+	testCases := []struct {
+		name    string
+		archive bool
+	}{
+		{
+			name: "delete",
+		},
+		{
+			name:    "archive",
+			archive: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := "/dynamic/pvc-123"
+			if err := os.MkdirAll(root+dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(root+dir+"/file", []byte("data"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := removeVolumeDirectory(root, dir, tc.archive); err != nil {
+				t.Fatalf("removeVolumeDirectory failed: %v", err)
+			}
+			if _, err := os.Stat(root + dir); !os.IsNotExist(err) {
+				t.Fatalf("Expected directory to be removed, got %v", err)
+			}
+			archived, err := filepath.Glob(root + "/dynamic/" + archivedDirPrefix + "pvc-123-*/file")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(archived) == 1) != tc.archive {
+				t.Fatalf("Expected directory to be archived: %v, got %v", tc.archive, archived)
+			}
+
+			// The directory was already removed.
+			if err := removeVolumeDirectory(root, dir, tc.archive); err != nil {
+				t.Fatalf("removeVolumeDirectory of missing directory failed: %v", err)
+			}
+		})
+	}
+}
+
 func TestControllerGetCapabilities(t *testing.T) {
 	var endpoint = "endpoint"
 	mockCtl := gomock.NewController(t)
@@ -0,0 +1,183 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// readDirBatch is the number of entries read from a directory at once, between which the walk may stop.
+const readDirBatch = 1024
+
+// fsStats is the space and the inodes of the file system a path is on, as reported by statfs.
+type fsStats struct {
+	available  int64
+	capacity   int64
+	inodes     int64
+	inodesFree int64
+}
+
+// diskUsage is the space and the inodes used by the files under a directory.
+type diskUsage struct {
+	bytes  int64
+	inodes int64
+}
+
+func statFS(path string) (fsStats, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return fsStats{}, &fs.PathError{Op: "statfs", Path: path, Err: err}
+	}
+	return fsStats{
+		available:  int64(st.Bavail) * int64(st.Bsize),
+		capacity:   int64(st.Blocks) * int64(st.Bsize),
+		inodes:     int64(st.Files),
+		inodesFree: int64(st.Ffree),
+	}, nil
+}
+
+// diskUsageWalker computes the space and the inodes used by the files under a directory, like du. Reading a
+// directory is a round trip to the NFS server, so the subdirectories are read by up to concurrency goroutines at once.
+// The files with several hard links are only counted once, and the files removed during the walk are ignored.
+type diskUsageWalker struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// sem holds a token for each goroutine walking a subdirectory, besides the caller of walkDiskUsage.
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu    sync.Mutex
+	usage diskUsage
+	links map[[2]uint64]bool
+	err   error
+}
+
+// walkDiskUsage returns the usage of the files under root, root included. It stops at the first error, or once ctx
+// is done.
+func walkDiskUsage(ctx context.Context, root string, concurrency int) (diskUsage, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := &diskUsageWalker{
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, concurrency-1),
+		links:  map[[2]uint64]bool{},
+	}
+	var st syscall.Stat_t
+	if err := syscall.Lstat(root, &st); err != nil {
+		return diskUsage{}, &fs.PathError{Op: "lstat", Path: root, Err: err}
+	}
+	w.count(&st)
+	w.wg.Add(1)
+	w.walkDir(root)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return diskUsage{}, w.err
+	}
+	// The walk may have been cut short.
+	if err := ctx.Err(); err != nil {
+		return diskUsage{}, err
+	}
+	return w.usage, nil
+}
+
+func (w *diskUsageWalker) walkDir(dir string) {
+	defer w.wg.Done()
+	f, err := os.Open(dir)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	defer f.Close()
+	for w.ctx.Err() == nil {
+		entries, err := f.ReadDir(readDirBatch)
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			var st syscall.Stat_t
+			if err := syscall.Lstat(path, &st); err != nil {
+				w.fail(&fs.PathError{Op: "lstat", Path: path, Err: err})
+				continue
+			}
+			w.count(&st)
+			if entry.IsDir() {
+				w.wg.Add(1)
+				w.spawn(path)
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			w.fail(err)
+			return
+		}
+	}
+}
+
+// spawn walks dir in a new goroutine if fewer than concurrency are running, and in the current one otherwise.
+func (w *diskUsageWalker) spawn(dir string) {
+	select {
+	case w.sem <- struct{}{}:
+		go func() {
+			defer func() { <-w.sem }()
+			w.walkDir(dir)
+		}()
+	default:
+		w.walkDir(dir)
+	}
+}
+
+func (w *diskUsageWalker) count(st *syscall.Stat_t) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if st.Mode&syscall.S_IFMT != syscall.S_IFDIR && uint64(st.Nlink) > 1 {
+		link := [2]uint64{uint64(st.Dev), st.Ino}
+		if w.links[link] {
+			return
+		}
+		w.links[link] = true
+	}
+	// Like du, the space allocated to the file is counted, in units of 512 bytes whatever the block size.
+	w.usage.bytes += st.Blocks * 512
+	w.usage.inodes++
+}
+
+// fail records the first error of the walk, and stops it.
+func (w *diskUsageWalker) fail(err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+		w.cancel()
+	}
+}
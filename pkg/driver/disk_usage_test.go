@@ -0,0 +1,96 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	data := make([]byte, 64*1024)
+	for i := 0; i < 3; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir%d", i), "nested")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	used, err := walkDiskUsage(context.Background(), root, 1)
+	if err != nil {
+		t.Fatalf("Failed to walk %s: %v", root, err)
+	}
+	if used.bytes < 3*int64(len(data)) {
+		t.Fatalf("Expected at least %d bytes used, got %d", 3*len(data), used.bytes)
+	}
+	// The root, 3 directories with a nested directory each, and 3 files.
+	if used.inodes != 10 {
+		t.Fatalf("Expected 10 inodes used, got %d", used.inodes)
+	}
+	for _, concurrency := range []int{0, 2, 8} {
+		if usedConcurrently, err := walkDiskUsage(context.Background(), root, concurrency); err != nil || usedConcurrently != used {
+			t.Fatalf("Expected %+v used with concurrency %d, got %+v, %v", used, concurrency, usedConcurrently, err)
+		}
+	}
+
+	// A file with several hard links is only counted once.
+	if err := os.Link(filepath.Join(root, "dir0", "nested", "file"), filepath.Join(root, "dir1", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if usedWithLink, err := walkDiskUsage(context.Background(), root, 4); err != nil || usedWithLink != used {
+		t.Fatalf("Expected %+v used with a hard link, got %+v, %v", used, usedWithLink, err)
+	}
+}
+
+func TestWalkDiskUsageCancelled(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := walkDiskUsage(ctx, root, 2); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected cancelled walk to fail with %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestWalkDiskUsageMissingRoot(t *testing.T) {
+	if _, err := walkDiskUsage(context.Background(), filepath.Join(t.TempDir(), "missing"), 2); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected walk of missing root to fail with %v, got %v", os.ErrNotExist, err)
+	}
+}
+
+func TestStatFS(t *testing.T) {
+	stats, err := statFS(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to statfs: %v", err)
+	}
+	if stats.capacity <= 0 || stats.available > stats.capacity {
+		t.Fatalf("Expected available space within a positive capacity, got %+v", stats)
+	}
+	if stats.inodesFree > stats.inodes {
+		t.Fatalf("Expected free inodes within the total inodes, got %+v", stats)
+	}
+}
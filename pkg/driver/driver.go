@@ -18,12 +18,15 @@ package driver
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
 	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
@@ -37,8 +40,17 @@ const (
 	AgentNotReadyNodeTaintKey = "efs.csi.aws.com/agent-not-ready"
 )
 
+// The modes the driver runs in: the controller Deployment runs the controller service, the node DaemonSet runs the
+// node service, and AllMode runs both.
+const (
+	ControllerMode = "controller"
+	NodeMode       = "node"
+	AllMode        = "all"
+)
+
 type Driver struct {
 	endpoint                 string
+	mode                     string
 	nodeID                   string
 	srv                      *grpc.Server
 	mounter                  Mounter
@@ -52,31 +64,136 @@ type Driver struct {
 	gidAllocator             GidAllocator
 	deleteAccessPointRootDir bool
 	tags                     map[string]string
+	healthChecker            *healthChecker
+	healthAddress            string
+	eventRecorder            *volumeEventRecorder
+	mountLogPath             string
+	mountInfoPath            string
+	tunnelStateDir           string
+	kubeClient               kubernetes.Interface
+	pvs                      *pvLookup
+	metricsAddress           string
+	leaderElectionNamespace  string
+
+	orphanedAccessPointReconcileInterval time.Duration
+	orphanedAccessPointGracePeriod       time.Duration
+	deleteOrphanedAccessPoints           bool
+	orphanedRootDirScanInterval          time.Duration
+	orphanedRootDirScanDryRun            bool
+	orphanedRootDirGracePeriod           time.Duration
+	archiveDeletedDirectories            bool
+	sharedAccessPointLocks               sharedAccessPointLocks
+	clusterId                            string
+	foreignAccessPointDeletion           string
+	protectedAccessPointDeletion         string
+	provisioningPolicy                   *provisioningPolicy
+	roleMapping                          *roleMapping
+	mountPolicy                          *mountPolicy
+	inFlight                             inFlight
+	volumes                              volumeTracker
+	mountMonitor                         *mountMonitor
+	mountTimeout                         time.Duration
+	unmountTimeout                       time.Duration
 }
 
-func NewDriver(endpoint, efsUtilsCfgPath, efsUtilsStaticFilesPath, tags string, volMetricsOptIn bool, volMetricsRefreshPeriod float64, volMetricsFsRateLimit int, deleteAccessPointRootDir bool) *Driver {
+// NewDriver creates the driver from opts, which must be valid.
+func NewDriver(opts *DriverOptions) *Driver {
+	var eventRecorder *volumeEventRecorder
+	var pvs *pvLookup
+	kubeClient, err := cloud.DefaultKubernetesAPIClient()
+	if err == nil {
+		if opts.Mode != NodeMode {
+			pvs = newPVLookup(kubeClient)
+		}
+		eventRecorder = newVolumeEventRecorder(kubeClient, pvs)
+	} else {
+		klog.Warningf("Could not create Kubernetes client, Events will not be recorded: %v", err)
+	}
+
 	cloud, err := cloud.NewCloud()
 	if err != nil {
 		klog.Fatalln(err)
 	}
 
-	nodeCaps := SetNodeCapOptInFeatures(volMetricsOptIn)
-	watchdog := newExecWatchdog(efsUtilsCfgPath, efsUtilsStaticFilesPath, "amazon-efs-mount-watchdog")
-	return &Driver{
-		endpoint:                 endpoint,
+	provisioningPolicy, err := loadProvisioningPolicy(opts.Controller.ProvisioningPolicyFile)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	roleMapping, err := loadRoleMapping(opts.Controller.RoleMappingFile)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+	mountPolicy, err := loadMountPolicy(opts.Node.MountPolicyFile)
+	if err != nil {
+		klog.Fatalln(err)
+	}
+
+	volMetricsOptIn := opts.Node.VolMetricsOptIn || featureGates.Enabled(VolumeMetrics)
+	var nodeCaps []csi.NodeServiceCapability_RPC_Type
+	if opts.Mode != ControllerMode {
+		nodeCaps = SetNodeCapOptInFeatures(volMetricsOptIn)
+		if featureGates.Enabled(NodeStageVolume) {
+			nodeCaps = append(nodeCaps, csi.NodeServiceCapability_RPC_STAGE_UNSTAGE_VOLUME)
+		}
+		// kubelet only reads the condition of volumes along with their usage.
+		if volMetricsOptIn && opts.Node.MountCheckInterval.Duration > 0 {
+			nodeCaps = append(nodeCaps, csi.NodeServiceCapability_RPC_VOLUME_CONDITION)
+		}
+	}
+	watchdog := newExecWatchdog(opts.EfsUtils.ConfigPath, opts.EfsUtils.StaticFilesPath, opts.EfsUtils.SettingsFile, "amazon-efs-mount-watchdog")
+	// The controller does not run the watchdog.
+	var checkedWatchdog Watchdog
+	if opts.Mode != ControllerMode {
+		checkedWatchdog = watchdog
+	}
+	healthChecker := newHealthChecker(checkedWatchdog, opts.EfsUtils.ConfigPath, cloud, opts.Controller.EfsAPIHealthCheck)
+	d := &Driver{
+		endpoint:                 opts.Endpoint,
+		mode:                     opts.Mode,
 		nodeID:                   cloud.GetMetadata().GetInstanceID(),
 		mounter:                  newNodeMounter(),
 		efsWatchdog:              watchdog,
 		cloud:                    cloud,
 		nodeCaps:                 nodeCaps,
-		volStatter:               NewVolStatter(),
+		volStatter:               NewVolStatter(opts.Node.VolMetricsWalkConcurrency),
 		volMetricsOptIn:          volMetricsOptIn,
-		volMetricsRefreshPeriod:  volMetricsRefreshPeriod,
-		volMetricsFsRateLimit:    volMetricsFsRateLimit,
+		volMetricsRefreshPeriod:  opts.Node.VolMetricsRefreshPeriod,
+		volMetricsFsRateLimit:    opts.Node.VolMetricsFsRateLimit,
 		gidAllocator:             NewGidAllocator(),
-		deleteAccessPointRootDir: deleteAccessPointRootDir,
-		tags:                     parseTagsFromStr(strings.TrimSpace(tags)),
+		deleteAccessPointRootDir: opts.Controller.DeleteAccessPointRootDir,
+		tags:                     parseTagsFromStr(strings.TrimSpace(opts.Controller.Tags)),
+		healthChecker:            healthChecker,
+		healthAddress:            opts.Metrics.HealthAddress,
+		eventRecorder:            eventRecorder,
+		mountLogPath:             efsUtilsMountLogPath,
+		mountInfoPath:            procMountInfoPath,
+		tunnelStateDir:           efsUtilsStateDir,
+		kubeClient:               kubeClient,
+		pvs:                      pvs,
+		metricsAddress:           opts.Metrics.Address,
+		leaderElectionNamespace:  opts.Controller.LeaderElectionNamespace,
+
+		orphanedAccessPointReconcileInterval: opts.Controller.OrphanedAccessPointReconcileInterval.Duration,
+		orphanedAccessPointGracePeriod:       opts.Controller.OrphanedAccessPointGracePeriod.Duration,
+		deleteOrphanedAccessPoints:           opts.Controller.DeleteOrphanedAccessPoints,
+		orphanedRootDirScanInterval:          opts.Controller.OrphanedRootDirScanInterval.Duration,
+		orphanedRootDirScanDryRun:            opts.Controller.OrphanedRootDirScanDryRun,
+		orphanedRootDirGracePeriod:           opts.Controller.OrphanedRootDirGracePeriod.Duration,
+		archiveDeletedDirectories:            opts.Controller.ArchiveDeletedDirectories,
+		clusterId:                            opts.Controller.ClusterId,
+		foreignAccessPointDeletion:           opts.Controller.ForeignAccessPointDeletion,
+		protectedAccessPointDeletion:         opts.Controller.ProtectedAccessPointDeletion,
+		provisioningPolicy:                   provisioningPolicy,
+		roleMapping:                          roleMapping,
+		mountPolicy:                          mountPolicy,
+		volumes:                              volumeTracker{file: opts.Node.VolumeStateFile, mountInfoPath: procMountInfoPath, kubeletDir: opts.Node.KubeletDir},
+		mountTimeout:                         opts.Node.MountTimeout.Duration,
+		unmountTimeout:                       opts.Node.UnmountTimeout.Duration,
+	}
+	if opts.Mode != ControllerMode {
+		d.mountMonitor = newMountMonitor(opts.Node.MountCheckInterval.Duration, opts.Node.MountCheckTimeout.Duration, opts.Node.RemountStaleVolumes, opts.Node.MountTimeout.Duration, d.mounter, &d.inFlight, eventRecorder)
 	}
+	return d
 }
 
 func SetNodeCapOptInFeatures(volMetricsOptIn bool) []csi.NodeServiceCapability_RPC_Type {
@@ -114,30 +231,75 @@ func (d *Driver) Run() error {
 	d.srv = grpc.NewServer(opts...)
 
 	csi.RegisterIdentityServer(d.srv, d)
-	klog.Info("Registering Node Server")
-	csi.RegisterNodeServer(d.srv, d)
-	klog.Info("Registering Controller Server")
-	csi.RegisterControllerServer(d.srv, d)
+	if d.runsNode() {
+		klog.Info("Registering Node Server")
+		csi.RegisterNodeServer(d.srv, d)
+	}
+	if d.runsController() {
+		klog.Info("Registering Controller Server")
+		csi.RegisterControllerServer(d.srv, d)
+	}
 
-	klog.Info("Starting efs-utils watchdog")
-	if err := d.efsWatchdog.start(); err != nil {
-		return err
+	if d.runsNode() {
+		if err := d.volumes.load(d.mounter); err != nil {
+			klog.Warningf("Could not restore the published volumes: %v", err)
+		}
+		// The mounts of the restored volumes are monitored, but not remounted as their source is not known.
+		for volumeId, targets := range d.volumes.published() {
+			for _, target := range targets {
+				d.mountMonitor.watch(volumeId, target, nil, nil)
+			}
+		}
+		go d.mountMonitor.run(make(chan struct{}))
+		klog.Info("Starting efs-utils watchdog")
+		if err := d.efsWatchdog.start(); err != nil {
+			return err
+		}
+	} else {
+		// The controller mounts file systems to manage the directories of volumes, which needs the efs-utils config
+		// but not the watchdog.
+		if err := d.efsWatchdog.setup(GetVersion().EfsClientSource); err != nil {
+			return err
+		}
 	}
 
+	// Both services mount file systems, whose stunnel processes are reaped.
 	reaper := newReaper()
 	klog.Info("Starting reaper")
 	reaper.start()
 
-	// Remove taint from node to indicate driver startup success
-	// This is done at the last possible moment to prevent race conditions or false positive removals
-	go tryRemoveNotReadyTaintUntilSucceed(time.Second, func() error {
-		return removeNotReadyTaint(cloud.DefaultKubernetesAPIClient)
-	})
+	if err := d.startHTTPServers(); err != nil {
+		return err
+	}
+
+	if d.runsController() {
+		if err := d.startReconcilers(); err != nil {
+			return err
+		}
+	}
+
+	if d.runsNode() {
+		// Remove taint from node to indicate driver startup success
+		// This is done at the last possible moment to prevent race conditions or false positive removals
+		go tryRemoveNotReadyTaintUntilSucceed(time.Second, func() error {
+			return removeNotReadyTaint(cloud.DefaultKubernetesAPIClient)
+		})
+	}
 
 	klog.Infof("Listening for connections on address: %#v", listener.Addr())
 	return d.srv.Serve(listener)
 }
 
+// runsController tells whether the driver runs the controller service. A driver without a mode runs both services.
+func (d *Driver) runsController() bool {
+	return d.mode != NodeMode
+}
+
+// runsNode tells whether the driver runs the node service.
+func (d *Driver) runsNode() bool {
+	return d.mode != ControllerMode
+}
+
 func parseTagsFromStr(tagStr string) map[string]string {
 	defer func() {
 		if r := recover(); r != nil {
@@ -157,3 +319,36 @@ func parseTagsFromStr(tagStr string) map[string]string {
 	}
 	return m
 }
+
+// startHTTPServers serves the health checks on the health address and the metrics on the metrics address,
+// from the same server if both addresses are the same.
+func (d *Driver) startHTTPServers() error {
+	muxes := map[string]*http.ServeMux{}
+	mux := func(address string) *http.ServeMux {
+		if _, ok := muxes[address]; !ok {
+			muxes[address] = http.NewServeMux()
+		}
+		return muxes[address]
+	}
+	if d.healthAddress != "" {
+		mux(d.healthAddress).Handle("/healthz", d.healthChecker.handler(true))
+		mux(d.healthAddress).Handle("/readyz", d.healthChecker.handler(false))
+	}
+	if d.metricsAddress != "" {
+		mux(d.metricsAddress).Handle("/metrics", metricsHandler())
+	}
+
+	for address, m := range muxes {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %v", address, err)
+		}
+		klog.Infof("Serving HTTP endpoints on address: %#v", listener.Addr())
+		go func(listener net.Listener, m *http.ServeMux) {
+			if err := http.Serve(listener, m); err != nil {
+				klog.Errorf("HTTP server stopped: %v", err)
+			}
+		}(listener, m)
+	}
+	return nil
+}
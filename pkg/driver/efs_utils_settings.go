@@ -0,0 +1,202 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// efsUtilsSettings overrides the values of efs-utils.conf, by section and key.
+type efsUtilsSettings map[string]map[string]string
+
+// configurableEfsUtilsSettings are the keys of efs-utils.conf that can be overridden, by section.
+var configurableEfsUtilsSettings = map[string][]string{
+	"DEFAULT": {
+		"logging_level",
+		"logging_max_bytes",
+		"logging_file_count",
+		"state_file_dir_mode",
+	},
+	"mount": {
+		"dns_name_format",
+		"dns_name_suffix",
+		"region",
+		"stunnel_debug_enabled",
+		"stunnel_logs_file",
+		"stunnel_cafile",
+		"stunnel_check_cert_hostname",
+		"stunnel_check_cert_validity",
+		"fips_mode_enabled",
+		"port_range_lower_bound",
+		"port_range_upper_bound",
+		"optimize_readahead",
+		"fall_back_to_mount_target_ip_address_enabled",
+		"disable_fetch_ec2_metadata_token",
+	},
+	"mount-watchdog": {
+		"enabled",
+		"poll_interval_sec",
+		"unmount_count_for_consistency",
+		"unmount_grace_period_sec",
+		"tls_cert_renewal_interval_min",
+		"stunnel_health_check_enabled",
+		"stunnel_health_check_interval_min",
+		"stunnel_health_check_command_timeout_sec",
+		"enable_version_check",
+	},
+	"cloudwatch-log": {
+		"enabled",
+		"log_group_name",
+		"retention_in_days",
+	},
+}
+
+// parseEfsUtilsSettings parses data, the content of file, a YAML file of sections holding keys and scalar values,
+// for example {"mount": {"port_range_lower_bound": 20049}}.
+func parseEfsUtilsSettings(file string, data []byte) (efsUtilsSettings, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse efs-utils settings %s: %v", file, err)
+	}
+	// Numbers are kept as written, instead of being turned into floats.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var sections map[string]map[string]interface{}
+	if err := decoder.Decode(&sections); err != nil {
+		return nil, fmt.Errorf("failed to parse efs-utils settings %s: %v", file, err)
+	}
+
+	settings := efsUtilsSettings{}
+	for section, values := range sections {
+		keys, ok := configurableEfsUtilsSettings[section]
+		if !ok {
+			return nil, fmt.Errorf("unknown section %q in efs-utils settings %s", section, file)
+		}
+		settings[section] = map[string]string{}
+		for key, value := range values {
+			if !containsString(keys, key) {
+				return nil, fmt.Errorf("unknown key %q in section %q of efs-utils settings %s", key, section, file)
+			}
+			switch value.(type) {
+			case string, json.Number, bool:
+			default:
+				return nil, fmt.Errorf("value of %s.%s in efs-utils settings %s must be a string, number or boolean", section, key, file)
+			}
+			s := fmt.Sprint(value)
+			if strings.ContainsAny(s, "\r\n") {
+				return nil, fmt.Errorf("value of %s.%s in efs-utils settings %s must be a single line", section, key, file)
+			}
+			settings[section][key] = s
+		}
+	}
+	return settings, nil
+}
+
+// apply overrides the values of the settings in config, an efs-utils.conf. Keys that are commented out are
+// uncommented, and missing keys are added at the end of their section, or of config with their section.
+func (s efsUtilsSettings) apply(config string) string {
+	if len(s) == 0 {
+		return config
+	}
+	var (
+		out     []string
+		section string
+		seen    = map[string]bool{}
+		applied = map[string]bool{}
+	)
+	// missing returns the settings of section that are not applied yet.
+	missing := func(section string) []string {
+		var lines []string
+		for key, value := range s[section] {
+			if !applied[section+"."+key] {
+				lines = append(lines, fmt.Sprintf("%s = %s", key, value))
+				applied[section+"."+key] = true
+			}
+		}
+		sort.Strings(lines)
+		return lines
+	}
+	// addMissing adds the missing settings of the current section after its last line that is not blank.
+	addMissing := func() {
+		lines := missing(section)
+		if len(lines) == 0 {
+			return
+		}
+		end := len(out)
+		for end > 0 && strings.TrimSpace(out[end-1]) == "" {
+			end--
+		}
+		out = append(out[:end], append(lines, out[end:]...)...)
+	}
+
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			addMissing()
+			section = strings.Trim(trimmed, "[]")
+			seen[section] = true
+			out = append(out, line)
+			continue
+		}
+		key, _, found := strings.Cut(strings.TrimLeft(trimmed, "# "), "=")
+		key = strings.TrimSpace(key)
+		if value, ok := s[section][key]; found && ok && !applied[section+"."+key] {
+			out = append(out, fmt.Sprintf("%s = %s", key, value))
+			applied[section+"."+key] = true
+			continue
+		}
+		out = append(out, line)
+	}
+	addMissing()
+
+	var sections []string
+	for section := range s {
+		if !seen[section] {
+			sections = append(sections, section)
+		}
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		if lines := missing(section); len(lines) > 0 {
+			for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+				out = out[:len(out)-1]
+			}
+			out = append(out, "", "["+section+"]")
+			out = append(out, lines...)
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// effectiveEfsUtilsConfig returns the settings of config, an efs-utils.conf, without its comments.
+func effectiveEfsUtilsConfig(config string) string {
+	var lines []string
+	for _, line := range strings.Split(config, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, trimmed)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEfsUtilsSettings(t *testing.T) {
+	testCases := []struct {
+		name      string
+		settings  string
+		expected  efsUtilsSettings
+		expectErr bool
+	}{
+		{
+			name:     "valid settings",
+			settings: "DEFAULT:\n  logging_level: DEBUG\nmount:\n  port_range_lower_bound: 20049\n  stunnel_check_cert_validity: true\nmount-watchdog:\n  poll_interval_sec: 1\n",
+			expected: efsUtilsSettings{
+				"DEFAULT":        {"logging_level": "DEBUG"},
+				"mount":          {"port_range_lower_bound": "20049", "stunnel_check_cert_validity": "true"},
+				"mount-watchdog": {"poll_interval_sec": "1"},
+			},
+		},
+		{
+			name:     "large number",
+			settings: "DEFAULT:\n  logging_max_bytes: 10485760000\n",
+			expected: efsUtilsSettings{
+				"DEFAULT": {"logging_max_bytes": "10485760000"},
+			},
+		},
+		{
+			name:      "unknown section",
+			settings:  "client-info:\n  source: custom\n",
+			expectErr: true,
+		},
+		{
+			name:      "unknown key",
+			settings:  "mount:\n  port_range_lower: 20049\n",
+			expectErr: true,
+		},
+		{
+			name:      "non scalar value",
+			settings:  "mount:\n  dns_name_suffix: [amazonaws.com]\n",
+			expectErr: true,
+		},
+		{
+			name:      "multi line value",
+			settings:  "mount:\n  dns_name_suffix: \"amazonaws.com\\n[mount]\"\n",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings, err := parseEfsUtilsSettings("efs-utils-settings.yaml", []byte(tc.settings))
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && !reflect.DeepEqual(settings, tc.expected) {
+				t.Fatalf("Expected settings %+v, got %+v", tc.expected, settings)
+			}
+		})
+	}
+}
+
+func TestApplyEfsUtilsSettings(t *testing.T) {
+	config := `
+[DEFAULT]
+logging_level = INFO
+
+[mount]
+#region = us-east-1
+port_range_lower_bound = 20049
+
+[cloudwatch-log]
+# enabled = true
+log_group_name = /aws/efs/utils
+`
+	settings := efsUtilsSettings{
+		"DEFAULT":        {"logging_level": "DEBUG"},
+		"mount":          {"region": "eu-west-1", "port_range_lower_bound": "30000", "port_range_upper_bound": "30400"},
+		"mount-watchdog": {"poll_interval_sec": "1"},
+		"cloudwatch-log": {"enabled": "true"},
+	}
+	expected := `
+[DEFAULT]
+logging_level = DEBUG
+
+[mount]
+region = eu-west-1
+port_range_lower_bound = 30000
+port_range_upper_bound = 30400
+
+[cloudwatch-log]
+enabled = true
+log_group_name = /aws/efs/utils
+
+[mount-watchdog]
+poll_interval_sec = 1
+`
+	if actual := settings.apply(config); actual != expected {
+		t.Fatalf("Unexpected config: want %s\nactual:%s", expected, actual)
+	}
+	if actual := efsUtilsSettings(nil).apply(config); actual != config {
+		t.Fatalf("Expected config without settings to be unchanged, got %s", actual)
+	}
+}
@@ -14,14 +14,17 @@ limitations under the License.
 package driver
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"k8s.io/klog/v2"
 )
@@ -148,6 +151,14 @@ retention_in_days = 14
 `
 
 	efsUtilsConfigFileName = "efs-utils.conf"
+	efsUtilsCAFileName     = "efs-utils.crt"
+
+	// If the process exits crashLoopThreshold times within crashLoopWindow, it is considered to be crash looping
+	crashLoopThreshold = 3
+	crashLoopWindow    = time.Minute
+
+	// How often the efs-utils settings file is checked for changes
+	efsUtilsSettingsReloadInterval = 10 * time.Second
 )
 
 // Watchdog defines the interface for process monitoring and supervising
@@ -155,8 +166,14 @@ type Watchdog interface {
 	// start starts the watch dog along with the process
 	start() error
 
+	// setup writes the efs-utils config without starting the process
+	setup(efsClientSource string) error
+
 	// stop stops the watch dog along with the process
 	stop()
+
+	// healthy returns an error if the process is not running or keeps crashing
+	healthy() error
 }
 
 // execWatchdog is a watch dog that monitors a process and restart it
@@ -172,8 +189,23 @@ type execWatchdog struct {
 	efsUtilsCfgPath string
 	// efs-utils static files path
 	efsUtilsStaticFilesPath string
+	// YAML file overriding the settings of efs-utils config, optional
+	efsUtilsSettingsFile string
+	// how often efsUtilsSettingsFile is checked for changes
+	settingsReloadInterval time.Duration
+	// the content of efsUtilsSettingsFile the config was rendered with, and the last content that was rejected
+	appliedSettings  []byte
+	rejectedSettings []byte
+	// the efs client source the config was rendered with
+	efsClientSource string
 	// stopCh indicates if it should be stopped
 	stopCh chan struct{}
+	// running indicates if the process is currently running
+	running bool
+	// exits records when the process exited within the last crashLoopWindow
+	exits []time.Time
+	// restarting indicates that the process was killed to be restarted, which is not a crash
+	restarting bool
 
 	mu sync.Mutex
 }
@@ -184,10 +216,12 @@ type efsUtilsConfig struct {
 	FipsEnabled     string
 }
 
-func newExecWatchdog(efsUtilsCfgPath, efsUtilsStaticFilesPath, cmd string, arg ...string) Watchdog {
+func newExecWatchdog(efsUtilsCfgPath, efsUtilsStaticFilesPath, efsUtilsSettingsFile, cmd string, arg ...string) Watchdog {
 	return &execWatchdog{
 		efsUtilsCfgPath:         efsUtilsCfgPath,
 		efsUtilsStaticFilesPath: efsUtilsStaticFilesPath,
+		efsUtilsSettingsFile:    efsUtilsSettingsFile,
+		settingsReloadInterval:  efsUtilsSettingsReloadInterval,
 		execCmd:                 cmd,
 		execArg:                 arg,
 		stopCh:                  make(chan struct{}),
@@ -200,6 +234,9 @@ func (w *execWatchdog) start() error {
 	}
 
 	go w.runLoop(w.stopCh)
+	if w.efsUtilsSettingsFile != "" {
+		go w.watchSettings(w.stopCh)
+	}
 
 	return nil
 }
@@ -245,7 +282,7 @@ func copyWithoutOverwriting(srcDir, dstDir string) error {
 			if err := copyFile(src, dst); err != nil {
 				return err
 			}
-		} else if filepath.Base(src) == "efs-utils.crt" {
+		} else if filepath.Base(src) == efsUtilsCAFileName {
 			klog.Infof("Copying %s ", dst)
 			if err := copyFile(src, dst); err != nil {
 				return err
@@ -275,22 +312,115 @@ func copyFile(src, dst string) error {
 }
 
 func (w *execWatchdog) updateConfig(efsClientSource string) error {
-	efsCfgTemplate := template.Must(template.New("efs-utils-config").Parse(efsUtilsConfigTemplate))
-	f, err := os.Create(filepath.Join(w.efsUtilsCfgPath, efsUtilsConfigFileName))
-	if err != nil {
-		return fmt.Errorf("cannot create config file %s for efs-utils. Error: %v", w.efsUtilsCfgPath, err)
+	var (
+		settings     efsUtilsSettings
+		settingsData []byte
+		err          error
+	)
+	if w.efsUtilsSettingsFile != "" {
+		settingsData, err = os.ReadFile(w.efsUtilsSettingsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read efs-utils settings: %v", err)
+		}
+		settings, err = parseEfsUtilsSettings(w.efsUtilsSettingsFile, settingsData)
+		if err != nil {
+			return err
+		}
 	}
-	defer f.Close()
+	efsCfgTemplate := template.Must(template.New("efs-utils-config").Parse(efsUtilsConfigTemplate))
 	// used on Fargate, IMDS queries suffice otherwise
 	region := os.Getenv("AWS_DEFAULT_REGION")
 	fipsEnabled := os.Getenv("FIPS_ENABLED")
 	efsCfg := efsUtilsConfig{EfsClientSource: efsClientSource, Region: region, FipsEnabled: fipsEnabled}
-	if err = efsCfgTemplate.Execute(f, efsCfg); err != nil {
+	var rendered strings.Builder
+	if err = efsCfgTemplate.Execute(&rendered, efsCfg); err != nil {
 		return fmt.Errorf("cannot update config %s for efs-utils. Error: %v", w.efsUtilsCfgPath, err)
 	}
+	config := settings.apply(rendered.String())
+
+	if err := writeFileAtomically(filepath.Join(w.efsUtilsCfgPath, efsUtilsConfigFileName), []byte(config)); err != nil {
+		return fmt.Errorf("cannot create config file %s for efs-utils. Error: %v", w.efsUtilsCfgPath, err)
+	}
+	w.efsClientSource = efsClientSource
+	w.appliedSettings = settingsData
+	klog.Infof("Effective efs-utils config:\n%s", effectiveEfsUtilsConfig(config))
 	return nil
 }
 
+// writeFileAtomically replaces the content of file by renaming a temporary file over it, so that mount.efs never
+// reads a partially written file.
+func writeFileAtomically(file string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// watchSettings re-renders the efs-utils config and restarts the process when the content of the settings file
+// changes, e.g. when the kubelet updates the ConfigMap it is mounted from. New mounts use the new config, while
+// existing mounts are not affected.
+func (w *execWatchdog) watchSettings(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(w.settingsReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			w.reloadSettings()
+		}
+	}
+}
+
+// reloadSettings updates the config if the settings file changed. Invalid settings are reported once and the
+// current config is kept.
+func (w *execWatchdog) reloadSettings() {
+	data, err := os.ReadFile(w.efsUtilsSettingsFile)
+	if err != nil {
+		klog.Errorf("Failed to read efs-utils settings %s: %v", w.efsUtilsSettingsFile, err)
+		return
+	}
+	if bytes.Equal(data, w.appliedSettings) || bytes.Equal(data, w.rejectedSettings) {
+		return
+	}
+	klog.Infof("efs-utils settings %s changed, updating the efs-utils config", w.efsUtilsSettingsFile)
+	if err := w.updateConfig(w.efsClientSource); err != nil {
+		klog.Errorf("Keeping the current efs-utils config: %v", err)
+		w.rejectedSettings = data
+		return
+	}
+	w.rejectedSettings = nil
+	w.restart()
+}
+
+// restart kills the process for runLoop to start it again with the current config. It is not counted as a crash.
+func (w *execWatchdog) restart() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running || w.cmd == nil || w.cmd.Process == nil {
+		return
+	}
+	klog.Infof("Restarting %s", w.execCmd)
+	w.restarting = true
+	if err := w.cmd.Process.Kill(); err != nil {
+		klog.Errorf("Failed to kill process: %s", err)
+		w.restarting = false
+	}
+}
+
 // stop kills the underlying process and stops the watchdog
 func (w *execWatchdog) stop() {
 	close(w.stopCh)
@@ -312,7 +442,7 @@ func (w *execWatchdog) runLoop(stopCh <-chan struct{}) {
 		select {
 		case <-stopCh:
 			klog.Info("stopping...")
-			break
+			return
 		default:
 			err := w.exec()
 			if err != nil {
@@ -327,16 +457,66 @@ func (w *execWatchdog) exec() error {
 	cmd.Stdout = newInfoRedirect(w.execCmd)
 	cmd.Stderr = newErrRedirect(w.execCmd)
 
-	w.cmd = cmd
-
 	w.mu.Lock()
+	w.cmd = cmd
 	err := cmd.Start()
 	if err != nil {
+		w.recordExit()
+		w.mu.Unlock()
 		return err
 	}
+	w.running = true
+	w.mu.Unlock()
+
+	err = cmd.Wait()
+
+	w.mu.Lock()
+	w.running = false
+	if w.restarting {
+		w.restarting = false
+		err = nil
+	} else {
+		w.recordExit()
+	}
 	w.mu.Unlock()
+	return err
+}
 
-	return cmd.Wait()
+// recordExit remembers when the process exited, forgetting exits older than crashLoopWindow.
+// The caller must hold w.mu.
+func (w *execWatchdog) recordExit() {
+	now := time.Now()
+	recent := w.exits[:0]
+	for _, t := range w.exits {
+		if now.Sub(t) < crashLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	w.exits = append(recent, now)
+}
+
+func (w *execWatchdog) healthy() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// A node plugin that is still starting must not fail its liveness probe.
+	if w.cmd == nil {
+		return nil
+	}
+
+	exits := 0
+	for _, t := range w.exits {
+		if time.Since(t) < crashLoopWindow {
+			exits++
+		}
+	}
+	if exits >= crashLoopThreshold {
+		return fmt.Errorf("%s exited %d times in the last %v", w.execCmd, exits, crashLoopWindow)
+	}
+	if !w.running {
+		return fmt.Errorf("%s is not running", w.execCmd)
+	}
+	return nil
 }
 
 type logRedirect struct {
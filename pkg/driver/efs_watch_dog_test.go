@@ -17,6 +17,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -141,11 +142,34 @@ func TestExecWatchdog(t *testing.T) {
 	defer os.RemoveAll(configDirName)
 	defer os.RemoveAll(staticFileDirName)
 
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300")
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300")
 	if err := w.start(); err != nil {
 		t.Fatalf("Failed to start %v", err)
 	}
 	time.Sleep(time.Second)
+	if err := w.healthy(); err != nil {
+		t.Fatalf("Expected watchdog to be healthy: %v", err)
+	}
+	w.stop()
+}
+
+func TestExecWatchdogCrashLoop(t *testing.T) {
+	configDirName := createTempDir(t)
+	staticFileDirName := createTempDir(t)
+	defer os.RemoveAll(configDirName)
+	defer os.RemoveAll(staticFileDirName)
+
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "false")
+	if err := w.healthy(); err != nil {
+		t.Fatalf("Expected watchdog that has not started to be healthy, got: %v", err)
+	}
+	if err := w.start(); err != nil {
+		t.Fatalf("Failed to start %v", err)
+	}
+	time.Sleep(time.Second)
+	if err := w.healthy(); err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Fatalf("Expected crash looping watchdog to be unhealthy, got: %v", err)
+	}
 	w.stop()
 }
 
@@ -182,7 +206,7 @@ func TestSetupWithEmptyConfigDirectory(t *testing.T) {
 	fileBContent := "dummyB"
 	createFile(t, staticFileDirName, fileBName, fileBContent)
 
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300").(*execWatchdog)
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300").(*execWatchdog)
 	efsClient := "k8s"
 	configFilePath := filepath.Join(configDirName, configFileName)
 	if err := w.setup(efsClient); err != nil {
@@ -215,7 +239,7 @@ func TestSetupWithNonEmptyConfigDirectory(t *testing.T) {
 	differentContent := "differentDummy"
 	createFile(t, configDirName, fileBName, differentContent)
 
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300").(*execWatchdog)
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300").(*execWatchdog)
 	efsClient := "k8s"
 	configFilePath := filepath.Join(configDirName, configFileName)
 	if err := w.setup(efsClient); err != nil {
@@ -233,7 +257,7 @@ func TestSetupWithNonexistentConfigDirectory(t *testing.T) {
 	configDirName := ""
 	staticFileDirName := createTempDir(t)
 	defer os.RemoveAll(staticFileDirName)
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300").(*execWatchdog)
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300").(*execWatchdog)
 	efsClient := "k8s"
 	if err := w.setup(efsClient); err == nil {
 		t.Fatalf("Expected failure since static files directory doesn't exist.")
@@ -244,7 +268,7 @@ func TestSetupWithNonexistentStaticFilesDirectory(t *testing.T) {
 	configDirName := createTempDir(t)
 	defer os.RemoveAll(configDirName)
 	staticFileDirName := ""
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300").(*execWatchdog)
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300").(*execWatchdog)
 	efsClient := "k8s"
 	if err := w.setup(efsClient); err == nil {
 		t.Fatalf("Expected failure since config directory doesn't exist.")
@@ -261,7 +285,7 @@ func TestSetupWithAdditionalDirectoryInStaticFilesDirectory(t *testing.T) {
 	_, err := ioutil.TempDir(staticFileDirName, "")
 	checkError(t, err)
 
-	w := newExecWatchdog(configDirName, staticFileDirName, "sleep", "300").(*execWatchdog)
+	w := newExecWatchdog(configDirName, staticFileDirName, "", "sleep", "300").(*execWatchdog)
 	efsClient := "k8s"
 	if err := w.setup(efsClient); err == nil {
 		t.Fatalf("Expected failure since config directory contains another directory.")
@@ -300,3 +324,75 @@ func TestWrite(t *testing.T) {
 		t.Errorf("Failed to Write in redirect: %v", err)
 	}
 }
+
+func TestSetupWithEfsUtilsSettings(t *testing.T) {
+	configDirName := createTempDir(t)
+	defer os.RemoveAll(configDirName)
+	staticFileDirName := createTempDir(t)
+	defer os.RemoveAll(staticFileDirName)
+
+	settingsFile := filepath.Join(createTempDir(t), "efs-utils-settings.yaml")
+	defer os.RemoveAll(filepath.Dir(settingsFile))
+	createFile(t, filepath.Dir(settingsFile), filepath.Base(settingsFile), "mount:\n  port_range_lower_bound: 30000\n  fall_back_to_mount_target_ip_address_enabled: false\n")
+
+	w := newExecWatchdog(configDirName, staticFileDirName, settingsFile, "sleep", "300").(*execWatchdog)
+	if err := w.setup("k8s"); err != nil {
+		t.Fatalf("Failed to set up with efs-utils settings: %v", err)
+	}
+	expected := strings.Replace(expectedEfsUtilsConfig, "port_range_lower_bound = 20049", "port_range_lower_bound = 30000", 1)
+	expected = strings.Replace(expected, "fall_back_to_mount_target_ip_address_enabled = true", "fall_back_to_mount_target_ip_address_enabled = false", 1)
+	verifyFileContent(t, filepath.Join(configDirName, configFileName), expected)
+
+	createFile(t, filepath.Dir(settingsFile), filepath.Base(settingsFile), "mount:\n  port_range_lower: 30000\n")
+	if err := w.setup("k8s"); err == nil {
+		t.Fatalf("Expected failure since efs-utils settings have an unknown key")
+	}
+}
+
+func TestExecWatchdogReloadsEfsUtilsSettings(t *testing.T) {
+	configDirName := t.TempDir()
+	staticFileDirName := t.TempDir()
+	settingsFile := filepath.Join(t.TempDir(), "efs-utils-settings.yaml")
+	createFile(t, filepath.Dir(settingsFile), filepath.Base(settingsFile), "DEFAULT:\n  logging_level: INFO\n")
+
+	w := newExecWatchdog(configDirName, staticFileDirName, settingsFile, "sleep", "300").(*execWatchdog)
+	w.settingsReloadInterval = 50 * time.Millisecond
+	if err := w.start(); err != nil {
+		t.Fatalf("Failed to start %v", err)
+	}
+	defer w.stop()
+	time.Sleep(200 * time.Millisecond)
+	pid := watchdogPid(t, w)
+
+	configFilePath := filepath.Join(configDirName, configFileName)
+	expected := strings.Replace(expectedEfsUtilsConfig, "source=k8s", "source="+GetVersion().EfsClientSource, 1)
+	expected = strings.Replace(expected, "logging_level = INFO", "logging_level = DEBUG", 1)
+	// Like the kubelet updating a ConfigMap volume, settings are replaced atomically.
+	checkError(t, writeFileAtomically(settingsFile, []byte("DEFAULT:\n  logging_level: DEBUG\n")))
+	time.Sleep(500 * time.Millisecond)
+	verifyFileContent(t, configFilePath, expected)
+	if newPid := watchdogPid(t, w); newPid == pid {
+		t.Fatalf("Expected process %d to be restarted", pid)
+	}
+	if err := w.healthy(); err != nil {
+		t.Fatalf("Expected restarted watchdog to be healthy: %v", err)
+	}
+	pid = watchdogPid(t, w)
+
+	// Invalid settings keep the current config and process.
+	checkError(t, writeFileAtomically(settingsFile, []byte("DEFAULT:\n  log_level: WARNING\n")))
+	time.Sleep(500 * time.Millisecond)
+	verifyFileContent(t, configFilePath, expected)
+	if newPid := watchdogPid(t, w); newPid != pid {
+		t.Fatalf("Expected process %d not to be restarted, got %d", pid, newPid)
+	}
+}
+
+func watchdogPid(t *testing.T, w *execWatchdog) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running {
+		t.Fatalf("Expected %s to be running", w.execCmd)
+	}
+	return w.cmd.Process.Pid
+}
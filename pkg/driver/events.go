@@ -0,0 +1,227 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+// Reasons of the Events recorded on PersistentVolumeClaims and PersistentVolumes when provisioning or
+// deleting a volume fails.
+const (
+	ReasonAccessDenied            = "AccessDenied"
+	ReasonAccessPointLimitReached = "AccessPointLimitReached"
+	ReasonFileSystemNotFound      = "FileSystemNotFound"
+	ReasonGidRangeExhausted       = "GidRangeExhausted"
+	ReasonPathTooLong             = "PathTooLong"
+
+	ReasonSharedAccessPointTagLimitReached = "SharedAccessPointTagLimitReached"
+	ReasonProvisioningPolicyViolation      = "ProvisioningPolicyViolation"
+
+	ReasonAccessPointOwnedByOtherCluster = "AccessPointOwnedByOtherCluster"
+	ReasonAccessPointDeletionProtected   = "AccessPointDeletionProtected"
+)
+
+// Reasons of the Events recorded on pods when mounting their volume fails.
+const (
+	ReasonMountAccessDenied        = "MountAccessDenied"
+	ReasonMountAccessPointNotFound = "MountAccessPointNotFound"
+	ReasonMountDNSResolutionFailed = "MountDNSResolutionFailed"
+	ReasonMountTLSFailed           = "MountTLSFailed"
+	ReasonMountTargetUnreachable   = "MountTargetUnreachable"
+)
+
+// Reasons of the Events recorded on pods when the mount of their volume becomes abnormal.
+const (
+	ReasonMountHung          = "MountHung"
+	ReasonMountStale         = "MountStale"
+	ReasonMountNotAccessible = "MountNotAccessible"
+)
+
+// Reasons of the Events recorded on StorageClasses by the reconcilers.
+const (
+	ReasonOrphanedAccessPointDeleted = "OrphanedAccessPointDeleted"
+	ReasonOrphanedAccessPoints       = "OrphanedAccessPoints"
+
+	ReasonOrphanedRootDirectories        = "OrphanedRootDirectories"
+	ReasonOrphanedRootDirectoriesDeleted = "OrphanedRootDirectoriesDeleted"
+)
+
+// Reasons of the Events recorded on PersistentVolumes by the reconcilers.
+const (
+	ReasonAccessPointOwnershipTransferred = "AccessPointOwnershipTransferred"
+)
+
+// eventHints tells the user how to fix the failure behind each reason.
+var eventHints = map[string]string{
+	ReasonAccessDenied:            "Ensure the IAM role of the controller, or the role passed in the StorageClass secret, allows the actions listed in docs/iam-policy-example.json.",
+	ReasonAccessPointLimitReached: fmt.Sprintf("The file system has reached the EFS limit of %d access points. Delete unused access points or use another file system.", cloud.AccessPointPerFsLimit),
+	ReasonFileSystemNotFound:      "Ensure the fileSystemId parameter of the StorageClass names a file system in the region and account of the driver.",
+	ReasonGidRangeExhausted:       "Every GID between gidRangeStart and gidRangeEnd is in use. Widen the range in the StorageClass or delete unused access points.",
+	ReasonPathTooLong:             "EFS limits access point paths to 100 characters and 4 subdirectories. Shorten the basePath or subPathPattern parameter of the StorageClass.",
+
+	ReasonSharedAccessPointTagLimitReached: fmt.Sprintf("Each volume using a shared access point is recorded in a tag, and EFS allows %d tags on an access point. Use another sharedAccessPointName for new volumes.", maxAccessPointTags),
+	ReasonProvisioningPolicyViolation:      "The provisioning policy of the driver does not allow the namespace of the PVC to use the fileSystemId, basePath, uid or gid of the StorageClass. Use a StorageClass allowed for the namespace, or ask the cluster administrators to update the policy.",
+
+	ReasonAccessPointDeletionProtected:   fmt.Sprintf("The access point is tagged %s=true. Remove the tag from the access point to delete it, then the PersistentVolume is deleted on the next retry.", DeletionProtectionTagKey),
+	ReasonAccessPointOwnedByOtherCluster: fmt.Sprintf("The access point is tagged %s with the ID of another cluster. Delete the PersistentVolume from that cluster, or annotate it with %s=true in this cluster to take ownership.", OwnerClusterTagKey, TakeOwnershipAnnotation),
+
+	ReasonMountAccessDenied:        "The IAM role of the node or the file system policy denied the mount. Ensure they allow elasticfilesystem:ClientMount, and elasticfilesystem:ClientWrite for read-write mounts.",
+	ReasonMountAccessPointNotFound: "Ensure the access point in the volumeHandle of the PersistentVolume exists and belongs to the file system.",
+	ReasonMountDNSResolutionFailed: "Ensure DNS resolution and DNS hostnames are enabled in the VPC and that the file system has a mount target in the availability zone of the node, or set the mounttargetip volume attribute.",
+	ReasonMountTLSFailed:           "The TLS tunnel to the file system could not be established. Check the stunnel logs in /var/log/amazon/efs of the efs-plugin container.",
+	ReasonMountTargetUnreachable:   "Ensure the security group of the mount target allows inbound NFS traffic (TCP port 2049) from the node.",
+
+	ReasonMountHung:          "The file system stopped responding to the node. Check the network path to the mount target, and the stunnel or efs-proxy logs in /var/log/amazon/efs of the efs-plugin container. Restart the pod, or enable remount-stale-volumes, to mount the volume again.",
+	ReasonMountStale:         "The mount of the volume is stale, e.g. after its TLS tunnel died. Restart the pod, or enable remount-stale-volumes, to mount the volume again.",
+	ReasonMountNotAccessible: "The mount of the volume can not be accessed. Check the logs of the efs-plugin container of the node.",
+}
+
+// volumeEventRecorder records Events on the PersistentVolumeClaims and PersistentVolumes the driver
+// works on. A nil volumeEventRecorder records nothing.
+type volumeEventRecorder struct {
+	client   kubernetes.Interface
+	recorder record.EventRecorder
+	// pvs finds the PersistentVolumes of deleted volumes. It is nil on the nodes, which do not delete volumes.
+	pvs *pvLookup
+}
+
+func newVolumeEventRecorder(client kubernetes.Interface, pvs *pvLookup) *volumeEventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return &volumeEventRecorder{
+		client:   client,
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: driverName}),
+		pvs:      pvs,
+	}
+}
+
+// pvcWarning records a warning on the PersistentVolumeClaim named in the CreateVolume parameters, which the
+// external-provisioner only passes when started with --extra-create-metadata.
+func (r *volumeEventRecorder) pvcWarning(ctx context.Context, volumeParams map[string]string, reason string, err error) {
+	if r == nil {
+		return
+	}
+	name, namespace := volumeParams[PvcName], volumeParams[PvcNamespace]
+	if name == "" || namespace == "" {
+		klog.V(4).Infof("Not recording %s event, PVC name or namespace is not in the volume parameters", reason)
+		return
+	}
+	pvc, getErr := r.client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, name, metav1.GetOptions{})
+	if getErr != nil {
+		klog.Warningf("Not recording %s event, could not get PVC %s/%s: %v", reason, namespace, name, getErr)
+		return
+	}
+	r.warning(pvc, reason, err)
+}
+
+// podWarning records a warning on the pod named in the NodePublishVolume volume context, which kubelet only
+// passes when the CSIDriver has podInfoOnMount set.
+func (r *volumeEventRecorder) podWarning(ctx context.Context, volContext map[string]string, reason string, err error) {
+	if r == nil {
+		return
+	}
+	name, namespace := volContext[PodName], volContext[PodNamespace]
+	if name == "" || namespace == "" {
+		klog.V(4).Infof("Not recording %s event, pod name or namespace is not in the volume context", reason)
+		return
+	}
+	pod, getErr := r.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if getErr != nil {
+		klog.Warningf("Not recording %s event, could not get pod %s/%s: %v", reason, namespace, name, getErr)
+		return
+	}
+	if uid := volContext[PodUID]; uid != "" && string(pod.UID) != uid {
+		klog.V(4).Infof("Not recording %s event, pod %s/%s was replaced", reason, namespace, name)
+		return
+	}
+	r.warning(pod, reason, err)
+}
+
+// mountWarning records a warning on the pod in the volume context of NodePublishVolume or, failing that, on the
+// PersistentVolume in the volume context, since NodeStageVolume is not given the pods it mounts the volume for.
+func (r *volumeEventRecorder) mountWarning(ctx context.Context, volContext map[string]string, reason string, err error) {
+	if r == nil {
+		return
+	}
+	if volContext[PodName] != "" && volContext[PodNamespace] != "" {
+		r.podWarning(ctx, volContext, reason, err)
+		return
+	}
+	name := volContext[VolumeContextPvName]
+	if name == "" {
+		klog.V(4).Infof("Not recording %s event, neither pod nor PV name is in the volume context", reason)
+		return
+	}
+	pv, getErr := r.client.CoreV1().PersistentVolumes().Get(ctx, name, metav1.GetOptions{})
+	if getErr != nil {
+		klog.Warningf("Not recording %s event, could not get PV %s: %v", reason, name, getErr)
+		return
+	}
+	r.warning(pv, reason, err)
+}
+
+// pvWarning records a warning on the PersistentVolume of volumeId. Deletion failures are recorded on the PV,
+// since its PersistentVolumeClaim is usually gone by then.
+func (r *volumeEventRecorder) pvWarning(ctx context.Context, volumeId, reason string, err error) {
+	if r == nil {
+		return
+	}
+	pv, getErr := r.pvs.get(ctx, volumeId)
+	if getErr != nil {
+		klog.Warningf("Not recording %s event, could not find the PV of volume %s: %v", reason, volumeId, getErr)
+		return
+	}
+	if pv == nil {
+		klog.V(4).Infof("Not recording %s event, no PV found for volume %s", reason, volumeId)
+		return
+	}
+	r.warning(pv, reason, err)
+}
+
+func (r *volumeEventRecorder) warning(object runtime.Object, reason string, err error) {
+	message := status.Convert(err).Message()
+	if hint, ok := eventHints[reason]; ok {
+		message = fmt.Sprintf("%s. %s", message, hint)
+	}
+	r.recorder.Event(object, corev1.EventTypeWarning, reason, message)
+}
+
+// provisioningFailed records err on the PersistentVolumeClaim being provisioned and returns it.
+func (d *Driver) provisioningFailed(ctx context.Context, volumeParams map[string]string, reason string, err error) error {
+	d.eventRecorder.pvcWarning(ctx, volumeParams, reason, err)
+	return err
+}
+
+// deletionFailed records err on the PersistentVolume being deleted and returns it.
+func (d *Driver) deletionFailed(ctx context.Context, volumeId, reason string, err error) error {
+	d.eventRecorder.pvWarning(ctx, volumeId, reason, err)
+	return err
+}
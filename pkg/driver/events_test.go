@@ -0,0 +1,149 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func newFakeVolumeEventRecorder(objects ...runtime.Object) (*volumeEventRecorder, *record.FakeRecorder) {
+	recorder := record.NewFakeRecorder(10)
+	client := fake.NewSimpleClientset(objects...)
+	return &volumeEventRecorder{
+		client:   client,
+		recorder: recorder,
+		pvs:      newPVLookup(client),
+	}, recorder
+}
+
+// expectEvent fails the test unless the next recorded event is a warning with reason, and no event is recorded if
+// reason is empty.
+func expectEvent(t *testing.T, recorder *record.FakeRecorder, reason string) {
+	t.Helper()
+	select {
+	case event := <-recorder.Events:
+		if reason == "" {
+			t.Fatalf("Expected no event, got %q", event)
+		}
+		if !strings.HasPrefix(event, corev1.EventTypeWarning+" "+reason+" ") {
+			t.Fatalf("Expected %s warning, got %q", reason, event)
+		}
+	default:
+		if reason != "" {
+			t.Fatalf("Expected %s warning, got no event", reason)
+		}
+	}
+}
+
+func TestPvcWarning(t *testing.T) {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc", Namespace: "default"},
+	}
+	err := status.Error(codes.InvalidArgument, "Proposed path '/a/b/c/d/e/f' EFS limit of 4 subdirectories")
+
+	testCases := []struct {
+		name         string
+		volumeParams map[string]string
+		expectEvent  bool
+	}{
+		{
+			name:         "success: event is recorded on the PVC",
+			volumeParams: map[string]string{PvcName: "pvc", PvcNamespace: "default"},
+			expectEvent:  true,
+		},
+		{
+			name:         "no event: PVC is not in the volume parameters",
+			volumeParams: map[string]string{},
+		},
+		{
+			name:         "no event: PVC does not exist",
+			volumeParams: map[string]string{PvcName: "other", PvcNamespace: "default"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, recorder := newFakeVolumeEventRecorder(pvc)
+			r.pvcWarning(context.Background(), tc.volumeParams, ReasonPathTooLong, err)
+			if !tc.expectEvent {
+				expectEvent(t, recorder, "")
+				return
+			}
+			event := <-recorder.Events
+			expected := "Warning PathTooLong Proposed path '/a/b/c/d/e/f' EFS limit of 4 subdirectories. " + eventHints[ReasonPathTooLong]
+			if event != expected {
+				t.Fatalf("Expected event %q, got %q", expected, event)
+			}
+		})
+	}
+}
+
+func TestPvWarning(t *testing.T) {
+	pv := func(name, driver, volumeHandle string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: volumeHandle},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		objects     []runtime.Object
+		expectEvent bool
+	}{
+		{
+			name:        "success: event is recorded on the PV of the volume",
+			objects:     []runtime.Object{pv("pv-1", "ebs.csi.aws.com", "fs-abcd1234::fsap-abcd1234xyz987"), pv("pv-2", driverName, "fs-abcd1234::fsap-abcd1234xyz987")},
+			expectEvent: true,
+		},
+		{
+			name:    "no event: no PV for the volume",
+			objects: []runtime.Object{pv("pv-1", driverName, "fs-abcd1234::fsap-other")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, recorder := newFakeVolumeEventRecorder(tc.objects...)
+			r.pvWarning(context.Background(), "fs-abcd1234::fsap-abcd1234xyz987", ReasonAccessDenied, status.Error(codes.Unauthenticated, "Access Denied"))
+			if tc.expectEvent {
+				expectEvent(t, recorder, ReasonAccessDenied)
+			} else {
+				expectEvent(t, recorder, "")
+			}
+		})
+	}
+}
+
+func TestNilVolumeEventRecorder(t *testing.T) {
+	var r *volumeEventRecorder
+	r.pvcWarning(context.Background(), map[string]string{PvcName: "pvc", PvcNamespace: "default"}, ReasonAccessDenied, nil)
+	r.pvWarning(context.Background(), "fs-abcd1234", ReasonAccessDenied, nil)
+	r.mountWarning(context.Background(), map[string]string{VolumeContextPvName: "pv"}, ReasonAccessDenied, nil)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sort"
+
+	"k8s.io/component-base/featuregate"
+)
+
+// The feature gates of the driver. New experimental behaviors get a gate, which starts as alpha and disabled,
+// moves to beta and enabled by default, and is locked to enabled at GA before it is removed.
+const (
+	// VolumeMetrics reports the usage of volumes through NodeGetVolumeStats, like --vol-metrics-opt-in.
+	VolumeMetrics featuregate.Feature = "VolumeMetrics"
+	// DeleteAccessPointRootDir deletes the root directory of access points in DeleteVolume, like
+	// --delete-access-point-root-dir.
+	DeleteAccessPointRootDir featuregate.Feature = "DeleteAccessPointRootDir"
+	// NodeStageVolume mounts each volume once per node in NodeStageVolume, and bind mounts it into the pods.
+	NodeStageVolume featuregate.Feature = "NodeStageVolume"
+)
+
+var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
+	VolumeMetrics:            {Default: false, PreRelease: featuregate.Alpha},
+	DeleteAccessPointRootDir: {Default: false, PreRelease: featuregate.Alpha},
+	NodeStageVolume:          {Default: false, PreRelease: featuregate.Alpha},
+}
+
+// featureGates are the feature gates of the driver, set once from the options by SetFeatureGates.
+var featureGates = newFeatureGates()
+
+func newFeatureGates() featuregate.MutableFeatureGate {
+	gates := featuregate.NewFeatureGate()
+	if err := gates.Add(defaultFeatureGates); err != nil {
+		panic(err)
+	}
+	return gates
+}
+
+// ValidateFeatureGates returns an error if gates sets unknown gates, or gates locked to their default.
+func ValidateFeatureGates(gates map[string]bool) error {
+	return newFeatureGates().SetFromMap(gates)
+}
+
+// SetFeatureGates sets the feature gates of the driver, and reports them through metrics.
+func SetFeatureGates(gates map[string]bool) error {
+	if err := featureGates.SetFromMap(gates); err != nil {
+		return err
+	}
+	for feature, spec := range defaultFeatureGates {
+		enabled := 0.0
+		if featureGates.Enabled(feature) {
+			enabled = 1
+		}
+		featureEnabled.WithLabelValues(string(feature), string(spec.PreRelease)).Set(enabled)
+	}
+	return nil
+}
+
+// KnownFeatures describes the feature gates, with their stage and default.
+func KnownFeatures() []string {
+	return newFeatureGates().KnownFeatures()
+}
+
+// enabledFeatureGates returns the names of the enabled feature gates, sorted.
+func enabledFeatureGates() []string {
+	var enabled []string
+	for feature := range defaultFeatureGates {
+		if featureGates.Enabled(feature) {
+			enabled = append(enabled, string(feature))
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/component-base/featuregate"
+)
+
+// setFeatureGatesDuringTest sets gates on fresh feature gates, and returns a function restoring the previous ones.
+func setFeatureGatesDuringTest(t *testing.T, gates map[string]bool) func() {
+	previous := featureGates
+	featureGates = newFeatureGates()
+	if err := SetFeatureGates(gates); err != nil {
+		t.Fatalf("Failed to set feature gates %v: %v", gates, err)
+	}
+	return func() {
+		featureGates = previous
+	}
+}
+
+func TestValidateFeatureGates(t *testing.T) {
+	testCases := []struct {
+		name      string
+		gates     map[string]bool
+		expectErr bool
+	}{
+		{
+			name: "no gates",
+		},
+		{
+			name:  "known gates",
+			gates: map[string]bool{string(VolumeMetrics): true, string(DeleteAccessPointRootDir): false},
+		},
+		{
+			name:      "unknown gate",
+			gates:     map[string]bool{"NodeStaging": true},
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateFeatureGates(tc.gates)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestSetFeatureGates(t *testing.T) {
+	defer setFeatureGatesDuringTest(t, map[string]bool{string(VolumeMetrics): true})()
+
+	if !featureGates.Enabled(VolumeMetrics) || featureGates.Enabled(DeleteAccessPointRootDir) {
+		t.Fatalf("Expected only %s to be enabled", VolumeMetrics)
+	}
+	if enabled := GetVersion().FeatureGates; !reflect.DeepEqual(enabled, []string{string(VolumeMetrics)}) {
+		t.Fatalf("Expected version to report enabled gates [%s], got %v", VolumeMetrics, enabled)
+	}
+	for feature, expected := range map[featuregate.Feature]float64{VolumeMetrics: 1, DeleteAccessPointRootDir: 0} {
+		stage := string(defaultFeatureGates[feature].PreRelease)
+		if value := testutil.ToFloat64(featureEnabled.WithLabelValues(string(feature), stage)); value != expected {
+			t.Errorf("Expected metric of %s to be %v, got %v", feature, expected, value)
+		}
+	}
+}
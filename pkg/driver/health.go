@@ -0,0 +1,180 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+const (
+	// efsAPIHealthCheckInterval limits how often the EFS API is called by readiness checks,
+	// since /readyz may be probed every few seconds.
+	efsAPIHealthCheckInterval = time.Minute
+	healthCheckTimeout        = 10 * time.Second
+)
+
+// healthCheck is a named check reported through the /readyz endpoint. Liveness checks are also reported
+// through Probe and the /healthz endpoint, and should only fail when restarting the plugin can help.
+type healthCheck struct {
+	name     string
+	liveness bool
+	check    func(ctx context.Context) error
+}
+
+type healthCheckResult struct {
+	name string
+	err  error
+}
+
+type healthChecker struct {
+	checks []healthCheck
+}
+
+// newHealthChecker returns the checks that apply to this plugin. The efs-utils checks are only added if
+// efsUtilsCfgPath is set, and the EFS API check only if checkEfsAPI is set.
+func newHealthChecker(watchdog Watchdog, efsUtilsCfgPath string, c cloud.Cloud, checkEfsAPI bool) *healthChecker {
+	h := &healthChecker{}
+	if watchdog != nil {
+		h.checks = append(h.checks, healthCheck{
+			name:     "efs-utils-watchdog",
+			liveness: true,
+			check: func(ctx context.Context) error {
+				return watchdog.healthy()
+			},
+		})
+	}
+	if efsUtilsCfgPath != "" {
+		h.checks = append(h.checks,
+			healthCheck{
+				name:     "efs-utils-config",
+				liveness: true,
+				check:    fileHealthCheck(filepath.Join(efsUtilsCfgPath, efsUtilsConfigFileName)),
+			},
+			healthCheck{
+				name:     "efs-utils-ca",
+				liveness: true,
+				check:    fileHealthCheck(filepath.Join(efsUtilsCfgPath, efsUtilsCAFileName)),
+			})
+	}
+	if checkEfsAPI && c != nil {
+		h.checks = append(h.checks, healthCheck{
+			name:  "efs-api",
+			check: cachedHealthCheck(efsAPIHealthCheckInterval, c.ValidateCredentials),
+		})
+	}
+	return h
+}
+
+// fileHealthCheck verifies that a file exists and is not empty.
+func fileHealthCheck(path string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", path)
+		}
+		if info.Size() == 0 {
+			return fmt.Errorf("%s is empty", path)
+		}
+		return nil
+	}
+}
+
+// cachedHealthCheck runs check at most once per interval and returns the last result in between.
+func cachedHealthCheck(interval time.Duration, check func(ctx context.Context) error) func(ctx context.Context) error {
+	var (
+		mu      sync.Mutex
+		lastRun time.Time
+		lastErr error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !lastRun.IsZero() && time.Since(lastRun) < interval {
+			return lastErr
+		}
+		lastErr = check(ctx)
+		lastRun = time.Now()
+		return lastErr
+	}
+}
+
+// run runs the checks, or only the liveness checks if livenessOnly is set. A nil healthChecker has no checks.
+func (h *healthChecker) run(ctx context.Context, livenessOnly bool) []healthCheckResult {
+	if h == nil {
+		return nil
+	}
+	var results []healthCheckResult
+	for _, c := range h.checks {
+		if livenessOnly && !c.liveness {
+			continue
+		}
+		results = append(results, healthCheckResult{name: c.name, err: c.check(ctx)})
+	}
+	return results
+}
+
+func healthy(results []healthCheckResult) bool {
+	for _, r := range results {
+		if r.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// handler serves the result of the checks in the same format as the Kubernetes components' health endpoints.
+func (h *healthChecker) handler(livenessOnly bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		results := h.run(ctx, livenessOnly)
+		var b strings.Builder
+		for _, result := range results {
+			if result.err != nil {
+				fmt.Fprintf(&b, "[-]%s failed: %v\n", result.name, result.err)
+			} else {
+				fmt.Fprintf(&b, "[+]%s ok\n", result.name)
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if !healthy(results) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, b.String())
+			fmt.Fprintf(w, "%s check failed\n", r.URL.Path[1:])
+			return
+		}
+		if _, ok := r.URL.Query()["verbose"]; ok {
+			fmt.Fprint(w, b.String())
+		}
+		fmt.Fprint(w, "ok")
+	})
+}
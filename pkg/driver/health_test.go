@@ -0,0 +1,255 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+)
+
+type unhealthyWatchdog struct {
+	mockWatchdog
+}
+
+func (w *unhealthyWatchdog) healthy() error {
+	return errors.New("amazon-efs-mount-watchdog is not running")
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	configDirName := createTempDir(t)
+	defer os.RemoveAll(configDirName)
+
+	testCases := []struct {
+		name          string
+		watchdog      Watchdog
+		cfgPath       string
+		checkEfsAPI   bool
+		expectedNames []string
+	}{
+		{
+			name:          "node checks",
+			watchdog:      &mockWatchdog{},
+			cfgPath:       configDirName,
+			expectedNames: []string{"efs-utils-watchdog", "efs-utils-config", "efs-utils-ca"},
+		},
+		{
+			name:          "node and controller checks",
+			watchdog:      &mockWatchdog{},
+			cfgPath:       configDirName,
+			checkEfsAPI:   true,
+			expectedNames: []string{"efs-utils-watchdog", "efs-utils-config", "efs-utils-ca", "efs-api"},
+		},
+		{
+			name:          "no config directory",
+			watchdog:      &mockWatchdog{},
+			expectedNames: []string{"efs-utils-watchdog"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHealthChecker(tc.watchdog, tc.cfgPath, cloud.NewFakeCloudProvider(), tc.checkEfsAPI)
+			var names []string
+			for _, c := range h.checks {
+				names = append(names, c.name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.expectedNames, ",") {
+				t.Fatalf("Expected checks %v, got %v", tc.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestFileHealthCheck(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	createFile(t, dir, efsUtilsConfigFileName, "[DEFAULT]")
+	createFile(t, dir, "empty", "")
+
+	testCases := []struct {
+		name      string
+		path      string
+		expectErr bool
+	}{
+		{name: "success: file exists", path: filepath.Join(dir, efsUtilsConfigFileName)},
+		{name: "fail: file does not exist", path: filepath.Join(dir, efsUtilsCAFileName), expectErr: true},
+		{name: "fail: file is empty", path: filepath.Join(dir, "empty"), expectErr: true},
+		{name: "fail: path is a directory", path: dir, expectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := fileHealthCheck(tc.path)(context.Background())
+			if tc.expectErr && err == nil {
+				t.Fatalf("Expected check of %s to fail", tc.path)
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCachedHealthCheck(t *testing.T) {
+	calls := 0
+	check := cachedHealthCheck(time.Hour, func(ctx context.Context) error {
+		calls++
+		return errors.New("Access denied")
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := check(context.Background()); err == nil {
+			t.Fatalf("Expected cached error")
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("Expected check to run once, ran %d times", calls)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	h := &healthChecker{
+		checks: []healthCheck{
+			{
+				name:     "live",
+				liveness: true,
+				check:    func(ctx context.Context) error { return nil },
+			},
+			{
+				name:  "ready",
+				check: func(ctx context.Context) error { return errors.New("not ready") },
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		path         string
+		livenessOnly bool
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "healthz only runs liveness checks",
+			path:         "/healthz",
+			livenessOnly: true,
+			expectedCode: http.StatusOK,
+			expectedBody: "ok",
+		},
+		{
+			name:         "healthz verbose",
+			path:         "/healthz?verbose",
+			livenessOnly: true,
+			expectedCode: http.StatusOK,
+			expectedBody: "[+]live ok\nok",
+		},
+		{
+			name:         "readyz reports failed checks",
+			path:         "/readyz",
+			expectedCode: http.StatusServiceUnavailable,
+			expectedBody: "[+]live ok\n[-]ready failed: not ready\nreadyz check failed\n",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.handler(tc.livenessOnly).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if rec.Code != tc.expectedCode {
+				t.Fatalf("Expected status %d, got %d", tc.expectedCode, rec.Code)
+			}
+			if rec.Body.String() != tc.expectedBody {
+				t.Fatalf("Expected body %q, got %q", tc.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProbe(t *testing.T) {
+	configDirName := createTempDir(t)
+	defer os.RemoveAll(configDirName)
+	createFile(t, configDirName, efsUtilsConfigFileName, "[DEFAULT]")
+	createFile(t, configDirName, efsUtilsCAFileName, "CA")
+
+	testCases := []struct {
+		name          string
+		watchdog      Watchdog
+		cfgPath       string
+		checkEfsAPI   bool
+		efsAPIErr     error
+		expectedReady bool
+	}{
+		{
+			name:          "ready: all checks pass",
+			watchdog:      &mockWatchdog{},
+			cfgPath:       configDirName,
+			checkEfsAPI:   true,
+			expectedReady: true,
+		},
+		{
+			name:          "not ready: watchdog is unhealthy",
+			watchdog:      &unhealthyWatchdog{},
+			cfgPath:       configDirName,
+			expectedReady: false,
+		},
+		{
+			name:          "not ready: config directory is broken",
+			watchdog:      &mockWatchdog{},
+			cfgPath:       filepath.Join(configDirName, "missing"),
+			expectedReady: false,
+		},
+		{
+			name:          "ready: EFS API access denied is only reported by /readyz",
+			watchdog:      &mockWatchdog{},
+			cfgPath:       configDirName,
+			checkEfsAPI:   true,
+			efsAPIErr:     cloud.ErrAccessDenied,
+			expectedReady: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			// The EFS API is not called by Probe.
+			mockCloud.EXPECT().ValidateCredentials(gomock.Any()).Return(tc.efsAPIErr).Times(0)
+
+			driver := &Driver{
+				healthChecker: newHealthChecker(tc.watchdog, tc.cfgPath, mockCloud, tc.checkEfsAPI),
+			}
+			resp, err := driver.Probe(context.Background(), &csi.ProbeRequest{})
+			if err != nil {
+				t.Fatalf("Probe failed: %v", err)
+			}
+			if resp.GetReady().GetValue() != tc.expectedReady {
+				t.Fatalf("Expected ready %v, got %v", tc.expectedReady, resp.GetReady().GetValue())
+			}
+		})
+	}
+}
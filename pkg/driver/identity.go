@@ -22,6 +22,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/protobuf/ptypes/wrappers"
 	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/util"
 )
 
@@ -36,21 +37,31 @@ func (d *Driver) GetPluginInfo(ctx context.Context, req *csi.GetPluginInfoReques
 
 func (d *Driver) GetPluginCapabilities(ctx context.Context, req *csi.GetPluginCapabilitiesRequest) (*csi.GetPluginCapabilitiesResponse, error) {
 	klog.V(5).Infof("GetPluginCapabilities: called with args %+v", util.SanitizeRequest(*req))
-	resp := &csi.GetPluginCapabilitiesResponse{
-		Capabilities: []*csi.PluginCapability{
-			{
-				Type: &csi.PluginCapability_Service_{
-					Service: &csi.PluginCapability_Service{
-						Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
-					},
+	resp := &csi.GetPluginCapabilitiesResponse{}
+	if d.runsController() {
+		resp.Capabilities = append(resp.Capabilities, &csi.PluginCapability{
+			Type: &csi.PluginCapability_Service_{
+				Service: &csi.PluginCapability_Service{
+					Type: csi.PluginCapability_Service_CONTROLLER_SERVICE,
 				},
 			},
-		},
+		})
 	}
 
 	return resp, nil
 }
 
 func (d *Driver) Probe(ctx context.Context, req *csi.ProbeRequest) (*csi.ProbeResponse, error) {
-	return &csi.ProbeResponse{}, nil
+	klog.V(5).Infof("Probe: called with args %+v", util.SanitizeRequest(*req))
+	// Probe is called by the livenessprobe sidecar, so it only runs the checks that restarting the plugin can fix.
+	results := d.healthChecker.run(ctx, true)
+	for _, r := range results {
+		if r.err != nil {
+			klog.Warningf("Probe: %s check failed: %v", r.name, r.err)
+		}
+	}
+
+	return &csi.ProbeResponse{
+		Ready: &wrappers.BoolValue{Value: healthy(results)},
+	}, nil
 }
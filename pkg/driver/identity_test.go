@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestGetPluginCapabilities(t *testing.T) {
+	testCases := []struct {
+		mode              string
+		expectsController bool
+	}{
+		{mode: "", expectsController: true},
+		{mode: AllMode, expectsController: true},
+		{mode: ControllerMode, expectsController: true},
+		{mode: NodeMode, expectsController: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.mode, func(t *testing.T) {
+			d := &Driver{mode: tc.mode}
+			resp, err := d.GetPluginCapabilities(context.Background(), &csi.GetPluginCapabilitiesRequest{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			hasController := false
+			for _, c := range resp.GetCapabilities() {
+				if c.GetService().GetType() == csi.PluginCapability_Service_CONTROLLER_SERVICE {
+					hasController = true
+				}
+			}
+			if hasController != tc.expectsController {
+				t.Fatalf("Expected controller service capability %v in mode %q, got %v", tc.expectsController, tc.mode, hasController)
+			}
+		})
+	}
+}
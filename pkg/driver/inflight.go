@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// inFlight tracks the paths the node service is mounting or unmounting, so that the operations on a path do not
+// run at once. Unlike sharedAccessPointLocks, it does not wait: kubelet retries the operations it started, so a
+// concurrent caller is rejected with Aborted. The zero value is ready to use.
+type inFlight struct {
+	mu sync.Mutex
+	// paths counts the holders of each path: the operation that locked it, and the mounts and unmounts that timed
+	// out but still run.
+	paths map[string]int
+}
+
+// tryLock marks the operation of volumeId on path as in flight, and returns the function ending it. It returns an
+// Aborted error if another operation on path is in flight.
+func (f *inFlight) tryLock(volumeId, path string) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.paths[path] > 0 {
+		return nil, status.Errorf(codes.Aborted, "An operation on volume %s at %q is already in progress", volumeId, path)
+	}
+	return f.holdLocked(path), nil
+}
+
+// hold keeps path locked until the returned function is called, even if the operation that locked it ends first.
+func (f *inFlight) hold(path string) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.holdLocked(path)
+}
+
+func (f *inFlight) holdLocked(path string) func() {
+	if f.paths == nil {
+		f.paths = map[string]int{}
+	}
+	f.paths[path]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.paths[path]--; f.paths[path] == 0 {
+				delete(f.paths, path)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInFlight(t *testing.T) {
+	var f inFlight
+
+	unlock, err := f.tryLock(volumeId, targetPath)
+	if err != nil {
+		t.Fatalf("Expected first operation to start, got %v", err)
+	}
+	if _, err := f.tryLock(volumeId, targetPath); status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected concurrent operation to be aborted, got %v", err)
+	}
+	unlockOther, err := f.tryLock(volumeId, stagingPath)
+	if err != nil {
+		t.Fatalf("Expected operation on another path to start, got %v", err)
+	}
+	unlockOther()
+
+	unlock()
+	unlock, err = f.tryLock(volumeId, targetPath)
+	if err != nil {
+		t.Fatalf("Expected operation to start after the previous one ended, got %v", err)
+	}
+	unlock()
+}
+
+func TestInFlightHold(t *testing.T) {
+	var f inFlight
+
+	unlock, err := f.tryLock(volumeId, targetPath)
+	if err != nil {
+		t.Fatalf("Expected first operation to start, got %v", err)
+	}
+	release := f.hold(targetPath)
+	unlock()
+	unlock()
+	if _, err := f.tryLock(volumeId, targetPath); status.Code(err) != codes.Aborted {
+		t.Fatalf("Expected held path to stay locked, got %v", err)
+	}
+
+	release()
+	unlock, err = f.tryLock(volumeId, targetPath)
+	if err != nil {
+		t.Fatalf("Expected operation to start once the path is released, got %v", err)
+	}
+	unlock()
+}
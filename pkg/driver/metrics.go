@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "efs_csi"
+
+var (
+	// metricsRegistry holds the metrics served on /metrics. The Go and process metrics of the default
+	// registry are not included.
+	metricsRegistry = prometheus.NewRegistry()
+
+	orphanedAccessPoints = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_access_points",
+		Help:      "Number of access points tagged by the driver that are not used by any PersistentVolume.",
+	}, []string{"file_system_id"})
+
+	orphanedAccessPointsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_access_points_deleted_total",
+		Help:      "Number of orphaned access points deleted by the driver.",
+	}, []string{"file_system_id"})
+
+	orphanedRootDirs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_root_directories",
+		Help:      "Number of directories under the basePath of StorageClasses that are not used by any access point, found by the last scan.",
+	}, []string{"file_system_id"})
+
+	orphanedRootDirBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_root_directory_bytes",
+		Help:      "Total size of the files in the orphaned root directories found by the last scan.",
+	}, []string{"file_system_id"})
+
+	orphanedRootDirsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "orphaned_root_directories_deleted_total",
+		Help:      "Number of orphaned root directories deleted by the driver.",
+	}, []string{"file_system_id"})
+
+	reconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_errors_total",
+		Help:      "Number of failed runs of the controller reconcilers.",
+	}, []string{"reconciler"})
+
+	publishedVolumes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "published_volumes",
+		Help:      "Number of volumes published to pods by the node plugin.",
+	})
+
+	abnormalMounts = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "abnormal_mounts",
+		Help:      "Number of mounts of the node plugin found stale or hung by the mount monitor.",
+	})
+
+	remounts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "remounts_total",
+		Help:      "Number of abnormal mounts the mount monitor mounted again, by result.",
+	}, []string{"result"})
+
+	volumeUsageWalkDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "volume_usage_walk_duration_seconds",
+		Help:      "Duration of the walks of the volumes computing their usage, by result: success, failure or cancelled.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10),
+	}, []string{"result"})
+
+	featureEnabled = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "feature_enabled",
+		Help:      "Whether a feature gate of the driver is enabled (1) or not (0). The stage is ALPHA, BETA or empty for GA.",
+	}, []string{"name", "stage"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		orphanedAccessPoints,
+		orphanedAccessPointsDeleted,
+		orphanedRootDirs,
+		orphanedRootDirBytes,
+		orphanedRootDirsDeleted,
+		reconcileErrors,
+		publishedVolumes,
+		abnormalMounts,
+		remounts,
+		volumeUsageWalkDuration,
+		featureEnabled,
+	)
+}
+
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
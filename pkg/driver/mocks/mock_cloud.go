@@ -136,6 +136,46 @@ func (mr *MockEfsMockRecorder) DescribeMountTargets(arg0, arg1 interface{}, arg2
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeMountTargets", reflect.TypeOf((*MockEfs)(nil).DescribeMountTargets), varargs...)
 }
 
+// TagResource mocks base method.
+func (m *MockEfs) TagResource(arg0 context.Context, arg1 *efs.TagResourceInput, arg2 ...func(*efs.Options)) (*efs.TagResourceOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TagResource", varargs...)
+	ret0, _ := ret[0].(*efs.TagResourceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TagResource indicates an expected call of TagResource.
+func (mr *MockEfsMockRecorder) TagResource(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagResource", reflect.TypeOf((*MockEfs)(nil).TagResource), varargs...)
+}
+
+// UntagResource mocks base method.
+func (m *MockEfs) UntagResource(arg0 context.Context, arg1 *efs.UntagResourceInput, arg2 ...func(*efs.Options)) (*efs.UntagResourceOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UntagResource", varargs...)
+	ret0, _ := ret[0].(*efs.UntagResourceOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UntagResource indicates an expected call of UntagResource.
+func (mr *MockEfsMockRecorder) UntagResource(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntagResource", reflect.TypeOf((*MockEfs)(nil).UntagResource), varargs...)
+}
+
 // MockCloud is a mock of Cloud interface.
 type MockCloud struct {
 	ctrl     *gomock.Controller
@@ -276,3 +316,45 @@ func (mr *MockCloudMockRecorder) ListAccessPoints(ctx, fileSystemId interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccessPoints", reflect.TypeOf((*MockCloud)(nil).ListAccessPoints), ctx, fileSystemId)
 }
+
+// TagAccessPoint mocks base method.
+func (m *MockCloud) TagAccessPoint(ctx context.Context, accessPointId string, tags map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TagAccessPoint", ctx, accessPointId, tags)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TagAccessPoint indicates an expected call of TagAccessPoint.
+func (mr *MockCloudMockRecorder) TagAccessPoint(ctx, accessPointId, tags interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TagAccessPoint", reflect.TypeOf((*MockCloud)(nil).TagAccessPoint), ctx, accessPointId, tags)
+}
+
+// UntagAccessPoint mocks base method.
+func (m *MockCloud) UntagAccessPoint(ctx context.Context, accessPointId string, tagKeys []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UntagAccessPoint", ctx, accessPointId, tagKeys)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UntagAccessPoint indicates an expected call of UntagAccessPoint.
+func (mr *MockCloudMockRecorder) UntagAccessPoint(ctx, accessPointId, tagKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntagAccessPoint", reflect.TypeOf((*MockCloud)(nil).UntagAccessPoint), ctx, accessPointId, tagKeys)
+}
+
+// ValidateCredentials mocks base method.
+func (m *MockCloud) ValidateCredentials(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateCredentials", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateCredentials indicates an expected call of ValidateCredentials.
+func (mr *MockCloudMockRecorder) ValidateCredentials(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateCredentials", reflect.TypeOf((*MockCloud)(nil).ValidateCredentials), ctx)
+}
@@ -195,6 +195,34 @@ func (mr *MockMounterMockRecorder) Unmount(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmount", reflect.TypeOf((*MockMounter)(nil).Unmount), arg0)
 }
 
+// UnmountForce mocks base method.
+func (m *MockMounter) UnmountForce(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmountForce", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnmountForce indicates an expected call of UnmountForce.
+func (mr *MockMounterMockRecorder) UnmountForce(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmountForce", reflect.TypeOf((*MockMounter)(nil).UnmountForce), arg0)
+}
+
+// UnmountLazy mocks base method.
+func (m *MockMounter) UnmountLazy(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmountLazy", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UnmountLazy indicates an expected call of UnmountLazy.
+func (mr *MockMounterMockRecorder) UnmountLazy(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmountLazy", reflect.TypeOf((*MockMounter)(nil).UnmountLazy), arg0)
+}
+
 // canSafelySkipMountPointCheck mocks base method.
 func (m *MockMounter) canSafelySkipMountPointCheck() bool {
 	m.ctrl.T.Helper()
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// efsUtilsMountLogPath is where mount.efs logs, inside the efs-plugin container.
+	efsUtilsMountLogPath = "/var/log/amazon/efs/mount.log"
+	// mountLogTailBytes is how much of the end of mount.log is searched for lines about a failed mount.
+	mountLogTailBytes = 64 * 1024
+	// mountLogMaxLines is how many of those lines are added to the error.
+	mountLogMaxLines = 5
+)
+
+// mountFailurePatterns classifies the output of mount.efs and its log. The more specific failures are matched
+// first, e.g. a missing access point is also reported as access denied by the server.
+var mountFailurePatterns = []struct {
+	reason  string
+	pattern *regexp.Regexp
+}{
+	{ReasonMountAccessPointNotFound, regexp.MustCompile(`(?i)AccessPointNotFound|access point \S+ (does not exist|not found|could not be found)`)},
+	{ReasonMountDNSResolutionFailed, regexp.MustCompile(`(?i)failed to resolve|cannot be resolved|name or service not known|temporary failure in name resolution`)},
+	{ReasonMountTLSFailed, regexp.MustCompile(`(?i)tls tunnel|ssl_?error|tls handshake|certificate verify failed|stunnel.*(failed|error)`)},
+	{ReasonMountTargetUnreachable, regexp.MustCompile(`(?i)port 2049|connection timed out|connection refused|no route to host|connection to the mount target`)},
+	{ReasonMountAccessDenied, regexp.MustCompile(`(?i)access denied|accessdenied|not authorized|unauthorized`)},
+}
+
+// classifyMountFailure returns the reason of the first pattern matching the mount error, then the mount.log lines,
+// or "" if the failure is not a common one. The log may also mention earlier mounts of the file system, so the
+// output of the failed mount takes precedence.
+func classifyMountFailure(mountErr error, logLines []string) string {
+	for _, output := range []string{mountErr.Error(), strings.Join(logLines, "\n")} {
+		for _, p := range mountFailurePatterns {
+			if p.pattern.MatchString(output) {
+				return p.reason
+			}
+		}
+	}
+	return ""
+}
+
+// readMountLog returns the last lines of the end of the mount.log at path that mention fileSystemId or accessPointId.
+func readMountLog(path, fileSystemId, accessPointId string) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		klog.V(4).Infof("Could not open %s: %v", path, err)
+		return nil
+	}
+	defer f.Close()
+
+	if info, err := f.Stat(); err == nil && info.Size() > mountLogTailBytes {
+		if _, err := f.Seek(-mountLogTailBytes, io.SeekEnd); err != nil {
+			klog.V(4).Infof("Could not seek %s: %v", path, err)
+			return nil
+		}
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		klog.V(4).Infof("Could not read %s: %v", path, err)
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if (fileSystemId != "" && strings.Contains(line, fileSystemId)) || (accessPointId != "" && strings.Contains(line, accessPointId)) {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	if len(lines) > mountLogMaxLines {
+		lines = lines[len(lines)-mountLogMaxLines:]
+	}
+	return lines
+}
+
+// mountFailed adds the mount.log lines about the file system to mountErr and records a warning with a hint on
+// the pod consuming the volume, or its PersistentVolume when staging it, if the failure is a common one.
+func (d *Driver) mountFailed(ctx context.Context, volContext map[string]string, fileSystemId, accessPointId, source, target string, mountErr error) error {
+	logLines := readMountLog(d.mountLogPath, fileSystemId, accessPointId)
+	message := fmt.Sprintf("Could not mount %q at %q: %v", source, target, mountErr)
+	if len(logLines) > 0 {
+		message = fmt.Sprintf("%s\nmount.log:\n%s", message, strings.Join(logLines, "\n"))
+	}
+	err := status.Error(codes.Internal, message)
+
+	if reason := classifyMountFailure(mountErr, logLines); reason != "" {
+		d.eventRecorder.mountWarning(ctx, volContext, reason, err)
+	}
+	return err
+}
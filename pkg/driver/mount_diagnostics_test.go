@@ -0,0 +1,187 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestClassifyMountFailure(t *testing.T) {
+	testCases := []struct {
+		name           string
+		mountErr       string
+		logLines       []string
+		expectedReason string
+	}{
+		{
+			name:           "dns resolution",
+			mountErr:       `Failed to resolve "fs-abc123.efs.us-east-1.amazonaws.com" - check that your file system ID is correct`,
+			expectedReason: ReasonMountDNSResolutionFailed,
+		},
+		{
+			name:           "port 2049 unreachable",
+			mountErr:       "mount.nfs4: Connection timed out",
+			expectedReason: ReasonMountTargetUnreachable,
+		},
+		{
+			name:           "tls handshake",
+			mountErr:       "Failed to initialize TLS tunnel for fs-abc123",
+			expectedReason: ReasonMountTLSFailed,
+		},
+		{
+			name:           "mount output takes precedence over mount.log",
+			mountErr:       "mount.nfs4: access denied by server while mounting 127.0.0.1:/",
+			logLines:       []string{"Access point fsap-abcd1234 does not exist"},
+			expectedReason: ReasonMountAccessDenied,
+		},
+		{
+			name:           "access point not found in mount output",
+			mountErr:       "AccessPointNotFound: Access point fsap-abcd1234 does not exist",
+			expectedReason: ReasonMountAccessPointNotFound,
+		},
+		{
+			name:           "iam denied",
+			mountErr:       "mount.nfs4: access denied by server while mounting 127.0.0.1:/",
+			expectedReason: ReasonMountAccessDenied,
+		},
+		{
+			name:           "classified from mount.log",
+			mountErr:       "exit status 32",
+			logLines:       []string{"Connection to the mount target IP address 10.0.0.1 failed"},
+			expectedReason: ReasonMountTargetUnreachable,
+		},
+		{
+			name:     "unknown failure",
+			mountErr: "exit status 32",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := classifyMountFailure(errors.New(tc.mountErr), tc.logLines)
+			if reason != tc.expectedReason {
+				t.Fatalf("Expected reason %q, got %q", tc.expectedReason, reason)
+			}
+		})
+	}
+}
+
+func TestReadMountLog(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+
+	var log strings.Builder
+	log.WriteString("mount.efs fs-other:/ /mnt\n")
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&log, "line %d fs-abc123\n", i)
+	}
+	log.WriteString("Access point fsap-abcd1234 does not exist\n")
+	createFile(t, dir, "mount.log", log.String())
+
+	testCases := []struct {
+		name          string
+		path          string
+		accessPointId string
+		expectedLines []string
+	}{
+		{
+			name:          "last lines about the file system",
+			path:          filepath.Join(dir, "mount.log"),
+			expectedLines: []string{"line 5 fs-abc123", "line 6 fs-abc123", "line 7 fs-abc123", "line 8 fs-abc123", "line 9 fs-abc123"},
+		},
+		{
+			name:          "lines about the access point",
+			path:          filepath.Join(dir, "mount.log"),
+			accessPointId: "fsap-abcd1234",
+			expectedLines: []string{"line 6 fs-abc123", "line 7 fs-abc123", "line 8 fs-abc123", "line 9 fs-abc123", "Access point fsap-abcd1234 does not exist"},
+		},
+		{
+			name: "missing log",
+			path: filepath.Join(dir, "missing.log"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := readMountLog(tc.path, "fs-abc123", tc.accessPointId)
+			if !reflect.DeepEqual(lines, tc.expectedLines) {
+				t.Fatalf("Expected lines %v, got %v", tc.expectedLines, lines)
+			}
+		})
+	}
+}
+
+func TestMountFailed(t *testing.T) {
+	dir := createTempDir(t)
+	defer os.RemoveAll(dir)
+	createFile(t, dir, "mount.log", "Connection to the mount target IP address 10.0.0.1 failed for fs-abc123\n")
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", UID: types.UID("uid")},
+	}
+	pv := &corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}}
+	testCases := []struct {
+		name           string
+		volContext     map[string]string
+		expectedReason string
+	}{
+		{
+			name:           "event is recorded on the pod",
+			volContext:     map[string]string{PodName: "pod", PodNamespace: "default", PodUID: "uid"},
+			expectedReason: ReasonMountTargetUnreachable,
+		},
+		{
+			name:       "no event: pod was replaced",
+			volContext: map[string]string{PodName: "pod", PodNamespace: "default", PodUID: "other"},
+		},
+		{
+			name:           "staging: event is recorded on the PV",
+			volContext:     map[string]string{VolumeContextPvName: "pv"},
+			expectedReason: ReasonMountTargetUnreachable,
+		},
+		{
+			name:       "no event: PV does not exist",
+			volContext: map[string]string{VolumeContextPvName: "other"},
+		},
+		{
+			name:       "no event: no pod or PV info",
+			volContext: map[string]string{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			eventRecorder, recorder := newFakeVolumeEventRecorder(pod, pv)
+			driver := &Driver{eventRecorder: eventRecorder, mountLogPath: filepath.Join(dir, "mount.log")}
+
+			err := driver.mountFailed(context.Background(), tc.volContext, "fs-abc123", "", "fs-abc123:/", targetPath, errors.New("exit status 32"))
+			expected := "Could not mount \"fs-abc123:/\" at \"/target/path\": exit status 32\nmount.log:\nConnection to the mount target IP address 10.0.0.1 failed for fs-abc123"
+			if !strings.HasSuffix(err.Error(), expected) {
+				t.Fatalf("Expected error %q, got %q", expected, err.Error())
+			}
+			expectEvent(t, recorder, tc.expectedReason)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"k8s.io/klog/v2"
+)
+
+// mountSpec is how a staging path was mounted, to mount it again.
+type mountSpec struct {
+	source  string
+	fsType  string
+	options []string
+}
+
+// monitoredMount is a path mounted by the node service, and its condition.
+type monitoredMount struct {
+	volumeId string
+	// spec is only set for staging paths. The targets of NodePublishVolume are not remounted, as the containers
+	// of their pods would keep the mount they started with, and neither are the mounts restored after a restart of
+	// the node plugin.
+	spec *mountSpec
+	// volContext names the PVC, pod and PV of the mount to record its Events on. It is nil for the mounts restored
+	// after a restart of the node plugin, which are only logged.
+	volContext map[string]string
+	abnormal   bool
+	message    string
+}
+
+// mountMonitor periodically stats the paths mounted by the node service, to find the mounts that are stale, e.g.
+// after their TLS tunnel died, or hung, e.g. when the NFS server stops responding. It reports them through the
+// VolumeCondition of NodeGetVolumeStats and, if remount is set, lazily unmounts the staging paths among them and
+// mounts their source again, so that the pods started from then on get a working mount. A nil mountMonitor monitors
+// nothing.
+type mountMonitor struct {
+	interval time.Duration
+	timeout  time.Duration
+	remount  bool
+	// mountTimeout bounds the remounts, which would block the monitor if the file system does not respond. It is
+	// the interval without a mount timeout, so that the next check still runs.
+	mountTimeout time.Duration
+	mounter      Mounter
+	inFlight     *inFlight
+	events       *volumeEventRecorder
+	// stat is os.Stat, replaced by tests.
+	stat func(path string) error
+
+	mu     sync.Mutex
+	mounts map[string]*monitoredMount
+	// pending are the paths whose stat has not returned yet. They are not stat again until it does, so that a hung
+	// mount does not pile up blocked goroutines.
+	pending map[string]bool
+}
+
+func newMountMonitor(interval, timeout time.Duration, remount bool, mountTimeout time.Duration, mounter Mounter, inFlight *inFlight, events *volumeEventRecorder) *mountMonitor {
+	if interval == 0 {
+		return nil
+	}
+	if mountTimeout == 0 {
+		mountTimeout = interval
+	}
+	return &mountMonitor{
+		interval:     interval,
+		timeout:      timeout,
+		remount:      remount,
+		mountTimeout: mountTimeout,
+		mounter:      mounter,
+		inFlight:     inFlight,
+		events:       events,
+		stat: func(path string) error {
+			_, err := os.Stat(path)
+			return err
+		},
+		mounts:  map[string]*monitoredMount{},
+		pending: map[string]bool{},
+	}
+}
+
+// watch starts monitoring the mount of volumeId at path.
+func (m *mountMonitor) watch(volumeId, path string, spec *mountSpec, volContext map[string]string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mounts[path] = &monitoredMount{volumeId: volumeId, spec: spec, volContext: volContext}
+	m.reportLocked()
+}
+
+// unwatch stops monitoring the mount at path.
+func (m *mountMonitor) unwatch(path string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.mounts, path)
+	m.reportLocked()
+}
+
+// condition returns the condition of the mount at path, or nil if it is not monitored.
+func (m *mountMonitor) condition(path string) *csi.VolumeCondition {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mount, ok := m.mounts[path]
+	if !ok {
+		return nil
+	}
+	if mount.abnormal {
+		return &csi.VolumeCondition{Abnormal: true, Message: mount.message}
+	}
+	return &csi.VolumeCondition{Abnormal: false, Message: "Volume is mounted and responding"}
+}
+
+// run checks the mounts every interval until stopCh is closed.
+func (m *mountMonitor) run(stopCh <-chan struct{}) {
+	if m == nil {
+		return
+	}
+	klog.Infof("Checking mounts every %v", m.interval)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			m.checkAll()
+		}
+	}
+}
+
+// checkAll checks every mount, and remounts the abnormal staging paths if remount is set.
+func (m *mountMonitor) checkAll() {
+	m.mu.Lock()
+	paths := make([]string, 0, len(m.mounts))
+	for path := range m.mounts {
+		paths = append(paths, path)
+	}
+	m.mu.Unlock()
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		err := m.check(path)
+		mount, changed := m.update(path, err)
+		if mount == nil || !mount.abnormal {
+			continue
+		}
+		if changed {
+			klog.Warningf("Mount of volume %s at %s is abnormal: %s", mount.volumeId, path, mount.message)
+			m.events.mountWarning(context.Background(), mount.volContext, abnormalMountReason(err), errors.New(mount.message))
+		}
+		if m.remount && mount.spec != nil {
+			m.recover(mount.volumeId, path, *mount.spec)
+		}
+	}
+}
+
+// check stats path, and returns an error if the stat fails or does not return within the timeout.
+func (m *mountMonitor) check(path string) error {
+	m.mu.Lock()
+	if m.pending[path] {
+		m.mu.Unlock()
+		return errMountHung
+	}
+	m.pending[path] = true
+	m.mu.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		err := m.stat(path)
+		m.mu.Lock()
+		delete(m.pending, path)
+		m.mu.Unlock()
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(m.timeout):
+		return errMountHung
+	}
+}
+
+var errMountHung = errors.New("stat did not return in time")
+
+// update records the result of the check of path. It returns a copy of the mount, or nil if it is no longer
+// monitored, and whether its condition changed.
+func (m *mountMonitor) update(path string, err error) (*monitoredMount, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mount, ok := m.mounts[path]
+	if !ok {
+		return nil, false
+	}
+	message := ""
+	switch {
+	case err == nil:
+	case errors.Is(err, errMountHung):
+		message = fmt.Sprintf("Mount is hung: stat did not return within %v", m.timeout)
+	case errors.Is(err, syscall.ESTALE):
+		message = fmt.Sprintf("Mount is stale: %v", err)
+	default:
+		message = fmt.Sprintf("Mount is not accessible: %v", err)
+	}
+	abnormal := err != nil
+	changed := abnormal != mount.abnormal
+	if changed && !abnormal {
+		klog.Infof("Mount of volume %s at %s is healthy again", mount.volumeId, path)
+	}
+	mount.abnormal, mount.message = abnormal, message
+	m.reportLocked()
+	copied := *mount
+	return &copied, changed
+}
+
+// recover lazily unmounts path, which detaches it even if it is busy or hung, and mounts its source again.
+func (m *mountMonitor) recover(volumeId, path string, spec mountSpec) {
+	done, err := m.inFlight.tryLock(volumeId, path)
+	if err != nil {
+		klog.V(4).Infof("Not remounting %s: %v", path, err)
+		return
+	}
+	defer done()
+	// The volume may have been unpublished since it was checked.
+	m.mu.Lock()
+	_, ok := m.mounts[path]
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	klog.Infof("Remounting %s at %s", spec.source, path)
+	if err := m.mounter.UnmountLazy(path); err != nil {
+		klog.Errorf("Could not unmount %s to remount it: %v", path, err)
+		remounts.WithLabelValues("failure").Inc()
+		return
+	}
+	err = runWithTimeout(context.Background(), m.mountTimeout, m.inFlight, path, func() error {
+		return m.mounter.Mount(spec.source, path, spec.fsType, spec.options)
+	})
+	if err != nil {
+		klog.Errorf("Could not remount %s at %s: %v", spec.source, path, err)
+		remounts.WithLabelValues("failure").Inc()
+		return
+	}
+	remounts.WithLabelValues("success").Inc()
+	klog.Infof("Remounted %s at %s", spec.source, path)
+}
+
+// reportLocked reports the number of abnormal mounts.
+func (m *mountMonitor) reportLocked() {
+	abnormal := 0
+	for _, mount := range m.mounts {
+		if mount.abnormal {
+			abnormal++
+		}
+	}
+	abnormalMounts.Set(float64(abnormal))
+}
+
+func abnormalMountReason(err error) string {
+	switch {
+	case errors.Is(err, errMountHung):
+		return ReasonMountHung
+	case errors.Is(err, syscall.ESTALE):
+		return ReasonMountStale
+	default:
+		return ReasonMountNotAccessible
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMountMonitor(t *testing.T) {
+	spec := &mountSpec{source: volumeId + ":/", fsType: "efs", options: []string{"tls"}}
+	testCases := []struct {
+		name          string
+		remount       bool
+		spec          *mountSpec
+		statErr       error
+		lock          bool
+		expectRemount bool
+		remountErr    error
+		expectMessage string
+	}{
+		{
+			name:          "healthy",
+			remount:       true,
+			spec:          spec,
+			expectMessage: "Volume is mounted and responding",
+		},
+		{
+			name:          "stale without remount",
+			spec:          spec,
+			statErr:       &fs.PathError{Op: "stat", Path: targetPath, Err: syscall.ESTALE},
+			expectMessage: "Mount is stale: stat /target/path: stale file handle",
+		},
+		{
+			name:          "stale with remount",
+			remount:       true,
+			spec:          spec,
+			statErr:       &fs.PathError{Op: "stat", Path: targetPath, Err: syscall.ESTALE},
+			expectRemount: true,
+			expectMessage: "Mount is stale: stat /target/path: stale file handle",
+		},
+		{
+			name:          "not accessible with failed remount",
+			remount:       true,
+			spec:          spec,
+			statErr:       &fs.PathError{Op: "stat", Path: targetPath, Err: syscall.ENOTCONN},
+			expectRemount: true,
+			remountErr:    fmt.Errorf("mount failed"),
+			expectMessage: "Mount is not accessible: stat /target/path: transport endpoint is not connected",
+		},
+		{
+			name:          "target or restored mount is not remounted",
+			remount:       true,
+			statErr:       &fs.PathError{Op: "stat", Path: targetPath, Err: syscall.ESTALE},
+			expectMessage: "Mount is stale: stat /target/path: stale file handle",
+		},
+		{
+			name:          "mount being unpublished is not remounted",
+			remount:       true,
+			spec:          spec,
+			statErr:       &fs.PathError{Op: "stat", Path: targetPath, Err: syscall.ESTALE},
+			lock:          true,
+			expectMessage: "Mount is stale: stat /target/path: stale file handle",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMounter := mocks.NewMockMounter(mockCtrl)
+			var locks inFlight
+			m := newMountMonitor(time.Minute, time.Second, tc.remount, 0, mockMounter, &locks, nil)
+			m.stat = func(path string) error { return tc.statErr }
+			m.watch(volumeId, targetPath, tc.spec, nil)
+
+			if tc.lock {
+				done, err := locks.tryLock(volumeId, targetPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer done()
+			}
+			result := "success"
+			if tc.remountErr != nil {
+				result = "failure"
+			}
+			remountsBefore := testutil.ToFloat64(remounts.WithLabelValues(result))
+			if tc.expectRemount {
+				mockMounter.EXPECT().UnmountLazy(targetPath).Return(nil)
+				mockMounter.EXPECT().Mount(spec.source, targetPath, spec.fsType, spec.options).Return(tc.remountErr)
+			}
+
+			m.checkAll()
+
+			condition := m.condition(targetPath)
+			if condition.Abnormal != (tc.statErr != nil) || condition.Message != tc.expectMessage {
+				t.Fatalf("Expected condition %q, got %+v", tc.expectMessage, condition)
+			}
+			expectedRemounts := remountsBefore
+			if tc.expectRemount {
+				expectedRemounts++
+			}
+			if value := testutil.ToFloat64(remounts.WithLabelValues(result)); value != expectedRemounts {
+				t.Fatalf("Expected %v %s remounts, got %v", expectedRemounts, result, value)
+			}
+		})
+	}
+}
+
+func TestMountMonitorHungMount(t *testing.T) {
+	m := newMountMonitor(time.Minute, 10*time.Millisecond, false, 0, nil, &inFlight{}, nil)
+	release := make(chan struct{})
+	stats := 0
+	m.stat = func(path string) error {
+		stats++
+		<-release
+		return nil
+	}
+	m.watch(volumeId, targetPath, nil, nil)
+
+	m.checkAll()
+	if condition := m.condition(targetPath); !condition.Abnormal || !strings.HasPrefix(condition.Message, "Mount is hung") {
+		t.Fatalf("Expected mount to be hung, got %+v", condition)
+	}
+	if value := testutil.ToFloat64(abnormalMounts); value != 1 {
+		t.Fatalf("Expected 1 abnormal mount, got %v", value)
+	}
+	// The mount is still hung while its first stat has not returned, which is not stat again.
+	m.checkAll()
+	if condition := m.condition(targetPath); !condition.Abnormal {
+		t.Fatalf("Expected mount to still be hung, got %+v", condition)
+	}
+
+	close(release)
+	for i := 0; i < 100; i++ {
+		m.mu.Lock()
+		pending := m.pending[targetPath]
+		m.mu.Unlock()
+		if !pending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.checkAll()
+	if condition := m.condition(targetPath); condition.Abnormal {
+		t.Fatalf("Expected mount to be healthy again, got %+v", condition)
+	}
+	if stats != 2 {
+		t.Fatalf("Expected 2 stats, got %d", stats)
+	}
+
+	m.unwatch(targetPath)
+	if condition := m.condition(targetPath); condition != nil {
+		t.Fatalf("Expected unwatched mount to have no condition, got %+v", condition)
+	}
+	if value := testutil.ToFloat64(abnormalMounts); value != 0 {
+		t.Fatalf("Expected no abnormal mount, got %v", value)
+	}
+}
+
+func TestMountMonitorOnlyRemountsStagingPaths(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockMounter := mocks.NewMockMounter(mockCtrl)
+	m := newMountMonitor(time.Minute, time.Second, true, 0, mockMounter, &inFlight{}, nil)
+	m.stat = func(path string) error { return syscall.ESTALE }
+	m.watch(volumeId, targetPath, nil, nil)
+	m.watch(volumeId, stagingPath, &mountSpec{source: volumeId + ":/", fsType: "efs", options: []string{"tls"}}, nil)
+
+	mockMounter.EXPECT().UnmountLazy(stagingPath).Return(nil)
+	mockMounter.EXPECT().Mount(volumeId+":/", stagingPath, "efs", []string{"tls"}).Return(nil)
+	m.checkAll()
+
+	// The target bind mounted from the staging path is only reported.
+	if condition := m.condition(targetPath); !condition.Abnormal {
+		t.Fatalf("Expected target to be abnormal, got %+v", condition)
+	}
+}
+
+func TestNilMountMonitor(t *testing.T) {
+	m := newMountMonitor(0, time.Second, true, 0, nil, nil, nil)
+	if m != nil {
+		t.Fatalf("Expected no monitor if the interval is 0")
+	}
+	m.watch(volumeId, targetPath, nil, nil)
+	if condition := m.condition(targetPath); condition != nil {
+		t.Fatalf("Expected no condition, got %+v", condition)
+	}
+	m.unwatch(targetPath)
+}
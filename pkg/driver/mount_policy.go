@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// mountPolicy restricts the mount options NodePublishVolume passes to mount.efs. The lists hold option names,
+// without their values.
+type mountPolicy struct {
+	// AllowedMountOptions are the only options allowed in the mountOptions of volumes, if not empty. The options
+	// added by the driver, like accesspoint and tls, are always allowed.
+	AllowedMountOptions []string `json:"allowedMountOptions,omitempty"`
+	// DeniedMountOptions are not allowed in the mountOptions of volumes.
+	DeniedMountOptions []string `json:"deniedMountOptions,omitempty"`
+	// RequireTLS rejects the volumes with encryptInTransit set to false.
+	RequireTLS bool `json:"requireTLS,omitempty"`
+	// RequireIAM rejects the volumes without the iam mount option.
+	RequireIAM bool `json:"requireIAM,omitempty"`
+	// MaxRsize and MaxWsize limit the rsize and wsize mount options, if not 0.
+	MaxRsize int64 `json:"maxRsize,omitempty"`
+	MaxWsize int64 `json:"maxWsize,omitempty"`
+}
+
+// loadMountPolicy reads the policy from file. There is no policy if file is empty.
+func loadMountPolicy(file string) (*mountPolicy, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mount policy: %v", err)
+	}
+	policy := &mountPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse mount policy %s: %v", file, err)
+	}
+	if policy.MaxRsize < 0 || policy.MaxWsize < 0 {
+		return nil, fmt.Errorf("maxRsize and maxWsize of mount policy %s must be at least 0", file)
+	}
+	klog.Infof("Loaded mount policy from %s: %+v", file, *policy)
+	return policy, nil
+}
+
+// check fails with InvalidArgument naming the violated rule unless the policy allows the mountFlags of a volume,
+// which the driver turned into mountOptions.
+func (p *mountPolicy) check(mountFlags, mountOptions []string) error {
+	if p == nil {
+		return nil
+	}
+	for _, flag := range mountFlags {
+		name := strings.ToLower(strings.SplitN(flag, "=", 2)[0])
+		if len(p.AllowedMountOptions) > 0 && !containsOption(p.AllowedMountOptions, name) {
+			return status.Errorf(codes.InvalidArgument, "Mount policy rule allowedMountOptions does not allow mount option %q", name)
+		}
+		if containsOption(p.DeniedMountOptions, name) {
+			return status.Errorf(codes.InvalidArgument, "Mount policy rule deniedMountOptions denies mount option %q", name)
+		}
+	}
+	if p.RequireTLS && !hasOption(mountOptions, "tls") {
+		return status.Error(codes.InvalidArgument, "Mount policy rule requireTLS does not allow encryptInTransit to be false")
+	}
+	if p.RequireIAM && !hasOption(mountOptions, "iam") {
+		return status.Error(codes.InvalidArgument, "Mount policy rule requireIAM requires the iam mount option")
+	}
+	for _, limit := range []struct {
+		rule   string
+		option string
+		max    int64
+	}{
+		{"maxRsize", "rsize", p.MaxRsize},
+		{"maxWsize", "wsize", p.MaxWsize},
+	} {
+		if limit.max == 0 {
+			continue
+		}
+		for _, o := range mountOptions {
+			value, ok := strings.CutPrefix(strings.ToLower(o), limit.option+"=")
+			if !ok {
+				continue
+			}
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "Mount option %s must be a number: %v", limit.option, err)
+			}
+			if size > limit.max {
+				return status.Errorf(codes.InvalidArgument, "Mount policy rule %s limits %s to %d, got %d", limit.rule, limit.option, limit.max, size)
+			}
+		}
+	}
+	return nil
+}
+
+func containsOption(options []string, name string) bool {
+	for _, o := range options {
+		if strings.EqualFold(o, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMountPolicy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    string
+		expected  *mountPolicy
+		expectErr bool
+	}{
+		{
+			name:   "valid policy",
+			policy: "deniedMountOptions: [awsprofile]\nrequireTLS: true\nmaxRsize: 1048576\n",
+			expected: &mountPolicy{
+				DeniedMountOptions: []string{"awsprofile"},
+				RequireTLS:         true,
+				MaxRsize:           1048576,
+			},
+		},
+		{
+			name:      "unknown field",
+			policy:    "requireEncryption: true\n",
+			expectErr: true,
+		},
+		{
+			name:      "negative limit",
+			policy:    "maxWsize: -1\n",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "mount-policy.yaml")
+			if err := os.WriteFile(file, []byte(tc.policy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			policy, err := loadMountPolicy(file)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			if !tc.expectErr && !reflect.DeepEqual(policy, tc.expected) {
+				t.Fatalf("Expected policy %+v, got %+v", tc.expected, policy)
+			}
+		})
+	}
+}
@@ -14,7 +14,9 @@ limitations under the License.
 package driver
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 
 	mount_utils "k8s.io/mount-utils"
 )
@@ -24,6 +26,10 @@ type Mounter interface {
 	mount_utils.Interface
 	MakeDir(pathname string) error
 	GetDeviceName(mountPath string) (string, int, error)
+	// UnmountForce unmounts target even if its server does not respond, like umount -f.
+	UnmountForce(target string) error
+	// UnmountLazy detaches target even if it is busy or does not respond, like umount -l.
+	UnmountLazy(target string) error
 }
 
 type NodeMounter struct {
@@ -49,3 +55,19 @@ func (m *NodeMounter) MakeDir(pathname string) error {
 func (m *NodeMounter) GetDeviceName(mountPath string) (string, int, error) {
 	return mount_utils.GetDeviceNameFromMount(m, mountPath)
 }
+
+func (m *NodeMounter) UnmountForce(target string) error {
+	return umount("-f", target)
+}
+
+func (m *NodeMounter) UnmountLazy(target string) error {
+	return umount("-l", target)
+}
+
+func umount(flag, target string) error {
+	output, err := exec.Command("umount", flag, target).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("umount %s %s failed: %v, output: %s", flag, target, err, output)
+	}
+	return nil
+}
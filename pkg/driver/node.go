@@ -19,6 +19,7 @@ package driver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -36,6 +37,26 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	mount_utils "k8s.io/mount-utils"
+)
+
+const (
+	// Pod information kubelet adds to the volume context of NodePublishVolume when the CSIDriver has podInfoOnMount set.
+	PodName               = "csi.storage.k8s.io/pod.name"
+	PodNamespace          = "csi.storage.k8s.io/pod.namespace"
+	PodUID                = "csi.storage.k8s.io/pod.uid"
+	PodServiceAccountName = "csi.storage.k8s.io/serviceaccount.name"
+	PodEphemeral          = "csi.storage.k8s.io/ephemeral"
+
+	// VolumeContextPvName is the name of the PersistentVolume CreateVolume adds to the volume context, so that
+	// NodeStageVolume, which has no pod information, can record mount failures on it.
+	VolumeContextPvName = "pvName"
+
+	// procMountInfoPath lists the mounts of the efs-plugin container, which shares those of kubelet.
+	procMountInfoPath = "/proc/self/mountinfo"
+	// efsUtilsStateDir is where mount.efs keeps the state of the TLS tunnels of its mounts, inside the efs-plugin
+	// container.
+	efsUtilsStateDir = "/var/run/efs"
 )
 
 var (
@@ -43,21 +64,109 @@ var (
 		csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
 		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
 	}
-	volumeIdCounter  = make(map[string]int)
 	supportedFSTypes = []string{"efs", ""}
 )
 
 func (d *Driver) NodeStageVolume(ctx context.Context, req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	klog.V(4).Infof("NodeStageVolume: called with args %+v", util.SanitizeRequest(*req))
+
+	volumeId := req.GetVolumeId()
+	if len(volumeId) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	stagingTarget := req.GetStagingTargetPath()
+	if len(stagingTarget) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Staging target path not provided")
+	}
+
+	volCap := req.GetVolumeCapability()
+	if err := d.checkVolumeCapability(volCap); err != nil {
+		return nil, err
+	}
+
+	done, err := d.inFlight.tryLock(volumeId, stagingTarget)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// The volume is mounted read-write once per node, NodePublishVolume bind mounts it read-only for the pods that
+	// need it.
+	volContext := req.GetVolumeContext()
+	m, err := d.parseVolumeMount(volumeId, volCap, volContext, false)
+	if err != nil {
+		return nil, err
+	}
+
+	_, refCount, err := d.mounter.GetDeviceName(stagingTarget)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check if volume is staged: %v", err)
+	}
+	if refCount > 0 {
+		klog.V(5).Infof("NodeStageVolume: %s is already staged at %s", volumeId, stagingTarget)
+		d.mountMonitor.watch(volumeId, stagingTarget, &mountSpec{source: m.source, fsType: "efs", options: m.options}, volContext)
+		return &csi.NodeStageVolumeResponse{}, nil
+	}
+
+	klog.V(5).Infof("NodeStageVolume: creating dir %s", stagingTarget)
+	if err := d.mounter.MakeDir(stagingTarget); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not create dir %q: %v", stagingTarget, err)
+	}
+
+	klog.V(5).Infof("NodeStageVolume: mounting %s at %s with options %v", m.source, stagingTarget, m.options)
+	if err := d.mount(ctx, m.source, stagingTarget, "efs", m.options); err != nil {
+		if isTimeout(err) {
+			return nil, mountTimeoutError(m.source, stagingTarget, d.mountTimeout)
+		}
+		os.Remove(stagingTarget)
+		return nil, d.mountFailed(ctx, volContext, m.fileSystemId, m.accessPointId, m.source, stagingTarget, err)
+	}
+	klog.V(5).Infof("NodeStageVolume: %s was staged at %s", volumeId, stagingTarget)
+	d.mountMonitor.watch(volumeId, stagingTarget, &mountSpec{source: m.source, fsType: "efs", options: m.options}, volContext)
+	return &csi.NodeStageVolumeResponse{}, nil
 }
 
 func (d *Driver) NodeUnstageVolume(ctx context.Context, req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+	klog.V(4).Infof("NodeUnstageVolume: called with args %+v", util.SanitizeRequest(*req))
+
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID not provided")
+	}
+
+	stagingTarget := req.GetStagingTargetPath()
+	if len(stagingTarget) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Staging target path not provided")
+	}
+
+	done, err := d.inFlight.tryLock(req.GetVolumeId(), stagingTarget)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	_, refCount, err := d.mounter.GetDeviceName(stagingTarget)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check if volume is staged: %v", err)
+	}
+	// From the spec: If the volume corresponding to the volume_id is not staged to the staging_target_path, the
+	// Plugin MUST reply 0 OK.
+	if refCount == 0 {
+		klog.V(5).Infof("NodeUnstageVolume: %s staging target not mounted", stagingTarget)
+		return &csi.NodeUnstageVolumeResponse{}, nil
+	}
+
+	klog.V(5).Infof("NodeUnstageVolume: unmounting %s", stagingTarget)
+	if err := d.unmount(ctx, req.GetVolumeId(), stagingTarget); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", stagingTarget, err)
+	}
+	klog.V(5).Infof("NodeUnstageVolume: %s unmounted", stagingTarget)
+	d.mountMonitor.unwatch(stagingTarget)
+	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
 func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolumeRequest) (*csi.NodePublishVolumeResponse, error) {
 	klog.V(4).Infof("NodePublishVolume: called with args %+v", util.SanitizeRequest(*req))
-	mountOptions := []string{}
 
 	target := req.GetTargetPath()
 	if len(target) == 0 {
@@ -65,23 +174,277 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 	}
 
 	volCap := req.GetVolumeCapability()
+	if err := d.checkVolumeCapability(volCap); err != nil {
+		return nil, err
+	}
+
+	volContext := req.GetVolumeContext()
+	m, err := d.parseVolumeMount(req.GetVolumeId(), volCap, volContext, req.GetReadonly())
+	if err != nil {
+		return nil, err
+	}
+
+	done, err := d.inFlight.tryLock(req.GetVolumeId(), target)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	// A staged volume is bind mounted from its staging target, so that the pods of the node share its mount.
+	source, fsType, mountOptions, device := m.source, "efs", m.options, m.source
+	if stagingTarget := req.GetStagingTargetPath(); stagingTarget != "" {
+		stagedDevice, refCount, err := d.mounter.GetDeviceName(stagingTarget)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to check if volume is staged: %v", err)
+		}
+		if refCount == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition, "Volume %s is not staged at %q", req.GetVolumeId(), stagingTarget)
+		}
+		source, fsType, mountOptions, device = stagingTarget, "", []string{"bind"}, stagedDevice
+		if req.GetReadonly() {
+			mountOptions = append(mountOptions, "ro")
+		}
+	}
+
+	// kubelet retries the publications that timed out, which must not stack mounts on the target.
+	mountedDevice, refCount, err := d.mounter.GetDeviceName(target)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check if volume is mounted: %v", err)
+	}
+	if refCount > 0 {
+		mounted := isMountedFrom(mountedDevice, device)
+		if mounted && req.GetStagingTargetPath() != "" {
+			mounted, err = isBindMountOf(d.mountInfoPath, target, req.GetStagingTargetPath())
+		} else if mounted && isTunnelDevice(mountedDevice) {
+			mounted, err = isTunnelMountOf(d.tunnelStateDir, target, m)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to check if volume is mounted: %v", err)
+		}
+		if !mounted {
+			return nil, status.Errorf(codes.FailedPrecondition, "Target %q is already mounted from %q, not %q", target, mountedDevice, device)
+		}
+		klog.V(5).Infof("NodePublishVolume: %s is already mounted", target)
+		d.volumes.add(req.GetVolumeId(), target)
+		d.mountMonitor.watch(req.GetVolumeId(), target, nil, volContext)
+		return &csi.NodePublishVolumeResponse{}, nil
+	}
+
+	klog.V(5).Infof("NodePublishVolume: creating dir %s", target)
+	if err := d.mounter.MakeDir(target); err != nil {
+		return nil, status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
+	}
+
+	klog.V(5).Infof("NodePublishVolume: mounting %s at %s with options %v", source, target, mountOptions)
+	if err := d.mount(ctx, source, target, fsType, mountOptions); err != nil {
+		if isTimeout(err) {
+			return nil, mountTimeoutError(source, target, d.mountTimeout)
+		}
+		os.Remove(target)
+		if fsType == "" {
+			return nil, status.Errorf(codes.Internal, "Could not bind mount %q at %q: %v", source, target, err)
+		}
+		return nil, d.mountFailed(ctx, volContext, m.fileSystemId, m.accessPointId, source, target, err)
+	}
+	klog.V(5).Infof("NodePublishVolume: %s was mounted", target)
+	d.mountMonitor.watch(req.GetVolumeId(), target, nil, volContext)
+
+	d.volumes.add(req.GetVolumeId(), target)
+
+	return &csi.NodePublishVolumeResponse{}, nil
+}
+
+// mount mounts source at target, and gives up once the mount timeout expires or ctx is done.
+func (d *Driver) mount(ctx context.Context, source, target, fsType string, options []string) error {
+	return runWithTimeout(ctx, d.mountTimeout, &d.inFlight, target, func() error {
+		return d.mounter.Mount(source, target, fsType, options)
+	})
+}
+
+// mountTimeoutError is the error of a mount that did not finish in time. The mount keeps running in the background
+// and, if it succeeds, the next retry of kubelet finds it.
+func mountTimeoutError(source, target string, timeout time.Duration) error {
+	return status.Errorf(codes.DeadlineExceeded, "Mounting %q at %q did not finish within %v or the deadline of the request", source, target, timeout)
+}
+
+// unmount unmounts target within the unmount timeout, after cancelling the computation of the usage of volumeId,
+// which keeps the volume busy. A mount that does not unmount in time, e.g. because its server does not respond, is
+// then unmounted with umount -f and, as a last resort, with umount -l, which detaches it even if it is busy. As ctx
+// may be done by then, each fallback gets its own unmount timeout. Without an unmount timeout, the fallbacks could
+// block forever too, so the unmount only gives up once ctx is done, and kubelet retries it.
+func (d *Driver) unmount(ctx context.Context, volumeId, target string) error {
+	d.volStatter.cancelComputation(volumeId)
+	err := runWithTimeout(ctx, d.unmountTimeout, &d.inFlight, target, func() error {
+		return d.mounter.Unmount(target)
+	})
+	if !isTimeout(err) || d.unmountTimeout == 0 {
+		return err
+	}
+	klog.Warningf("Unmounting %s did not finish within %v, forcing it", target, d.unmountTimeout)
+	err = runWithTimeout(context.Background(), d.unmountTimeout, &d.inFlight, target, func() error {
+		return d.mounter.UnmountForce(target)
+	})
+	if err == nil {
+		return nil
+	}
+	klog.Warningf("Could not force unmount %s, detaching it lazily: %v", target, err)
+	return runWithTimeout(context.Background(), d.unmountTimeout, &d.inFlight, target, func() error {
+		return d.mounter.UnmountLazy(target)
+	})
+}
+
+// runWithTimeout runs op on path, and gives up once timeout expires or ctx is done. A timeout of 0 only waits for
+// ctx. op keeps running in the background, with path held in locks until it returns, so that the retries of kubelet
+// do not run along with it.
+func runWithTimeout(ctx context.Context, timeout time.Duration, locks *inFlight, path string, op func() error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release := locks.hold(path)
+	result := make(chan error, 1)
+	go func() {
+		defer release()
+		result <- op()
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isTimeout tells whether err is the timeout of runWithTimeout.
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// unpublished stops tracking volumeId at target, and evicts the usage of the volume from the cache once it is no
+// longer published on the node.
+func (d *Driver) unpublished(volumeId, target string) {
+	d.mountMonitor.unwatch(target)
+	if d.volumes.remove(volumeId, target) && d.volMetricsOptIn {
+		klog.V(4).Infof("Evicting vol ID: %v, vol path : %v from cache", volumeId, target)
+		d.volStatter.removeFromCache(volumeId)
+	}
+}
+
+// isMountedFrom tells whether device, as listed in the mount table, may be a mount of source. mount.efs mounts through
+// a local stunnel or efs-proxy when encrypting in transit, and through the DNS name of the file system otherwise, so
+// only the paths of these mounts are compared. The devices of the mounts through a tunnel, which do not name their
+// file system, are checked by isTunnelMountOf.
+func isMountedFrom(device, source string) bool {
+	if device == source {
+		return true
+	}
+	deviceHost, devicePath, ok := strings.Cut(device, ":")
+	if !ok {
+		return false
+	}
+	fileSystemId, sourcePath, _ := strings.Cut(source, ":")
+	if path.Clean(devicePath) != path.Clean(sourcePath) {
+		return false
+	}
+	return deviceHost == "127.0.0.1" || strings.HasPrefix(deviceHost, fileSystemId+".")
+}
+
+// isTunnelDevice tells whether device, as listed in the mount table, is mounted through a local stunnel or efs-proxy.
+func isTunnelDevice(device string) bool {
+	return strings.HasPrefix(device, "127.0.0.1:")
+}
+
+// isTunnelMountOf tells whether target, mounted through a local stunnel or efs-proxy, is a mount of the file system
+// and the access point of m. mount.efs keeps a state file for the tunnel in stateDir, named after the file system,
+// the mount point with its slashes replaced by dots, and the port of the tunnel. The state file only records the
+// access point along with the client certificate of the tunnel, so the access point is not checked without one.
+func isTunnelMountOf(stateDir, target string, m *volumeMount) (bool, error) {
+	entries, err := os.ReadDir(stateDir)
+	if err != nil {
+		return false, err
+	}
+	prefix := m.fileSystemId + "." + strings.ReplaceAll(strings.TrimPrefix(path.Clean(target), "/"), "/", ".") + "."
+	for _, entry := range entries {
+		port, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(stateDir, entry.Name()))
+		if err != nil {
+			return false, err
+		}
+		var state struct {
+			AccessPoint *string `json:"accessPoint"`
+		}
+		if err := json.Unmarshal(content, &state); err != nil {
+			return false, fmt.Errorf("could not parse state file %s: %v", entry.Name(), err)
+		}
+		return state.AccessPoint == nil || *state.AccessPoint == m.accessPointId, nil
+	}
+	return false, nil
+}
+
+// isBindMountOf tells whether target is a bind mount of stagingTarget, i.e. whether both are mounts of the same
+// directory of the same file system in the mountinfo at mountInfoPath. Their devices are the same for every volume
+// mounted through a tunnel.
+func isBindMountOf(mountInfoPath, target, stagingTarget string) (bool, error) {
+	infos, err := mount_utils.ParseMountInfo(mountInfoPath)
+	if err != nil {
+		return false, err
+	}
+	var targetInfo, stagingInfo *mount_utils.MountInfo
+	// The last mount of a path is the one visible at it.
+	for i := range infos {
+		switch infos[i].MountPoint {
+		case path.Clean(target):
+			targetInfo = &infos[i]
+		case path.Clean(stagingTarget):
+			stagingInfo = &infos[i]
+		}
+	}
+	if targetInfo == nil || stagingInfo == nil {
+		return false, nil
+	}
+	return targetInfo.Major == stagingInfo.Major && targetInfo.Minor == stagingInfo.Minor && targetInfo.Root == stagingInfo.Root, nil
+}
+
+// checkVolumeCapability returns an InvalidArgument error if volCap is missing or not supported.
+func (d *Driver) checkVolumeCapability(volCap *csi.VolumeCapability) error {
 	if volCap == nil {
-		return nil, status.Error(codes.InvalidArgument, "Volume capability not provided")
+		return status.Error(codes.InvalidArgument, "Volume capability not provided")
 	}
 
 	if err := d.isValidVolumeCapabilities([]*csi.VolumeCapability{volCap}); err != nil {
-		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Volume capability not supported: %s", err))
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("Volume capability not supported: %s", err))
 	}
 
 	if volCap.GetMount() == nil {
-		return nil, status.Error(codes.InvalidArgument, "Volume capability access type must be mount")
+		return status.Error(codes.InvalidArgument, "Volume capability access type must be mount")
 	}
+	return nil
+}
+
+// volumeMount is how mount.efs mounts a volume.
+type volumeMount struct {
+	source        string
+	options       []string
+	fileSystemId  string
+	accessPointId string
+}
+
+// parseVolumeMount returns how to mount volumeId, from its volume context and mount flags, checked against the
+// mount policy.
+func (d *Driver) parseVolumeMount(volumeId string, volCap *csi.VolumeCapability, volContext map[string]string, readOnly bool) (*volumeMount, error) {
+	mountOptions := []string{}
 
 	// TODO when CreateVolume is implemented, it must use the same key names
 	subpath := "/"
 	encryptInTransit := true
 	crossAccountDNSEnabled := false
-	volContext := req.GetVolumeContext()
 	for k, v := range volContext {
 		switch strings.ToLower(k) {
 		//Deprecated
@@ -93,6 +456,8 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 			subpath = filepath.Join(subpath, v)
 		case "storage.kubernetes.io/csiprovisioneridentity":
 			continue
+		case PodName, PodNamespace, PodUID, strings.ToLower(PodServiceAccountName), PodEphemeral, strings.ToLower(VolumeContextPvName):
+			continue
 		case "encryptintransit":
 			var err error
 			encryptInTransit, err = strconv.ParseBool(v)
@@ -113,7 +478,7 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 		}
 	}
 
-	fsid, vpath, apid, err := parseVolumeId(req.GetVolumeId())
+	fsid, vpath, apid, err := parseVolumeId(volumeId)
 	if err != nil {
 		// parseVolumeId returns the appropriate error
 		return nil, err
@@ -146,7 +511,7 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 		mountOptions = append(mountOptions, CrossAccount)
 	}
 
-	if req.GetReadonly() {
+	if readOnly {
 		mountOptions = append(mountOptions, "ro")
 	}
 
@@ -193,28 +558,10 @@ func (d *Driver) NodePublishVolume(ctx context.Context, req *csi.NodePublishVolu
 			}
 		}
 	}
-	klog.V(5).Infof("NodePublishVolume: creating dir %s", target)
-	if err := d.mounter.MakeDir(target); err != nil {
-		return nil, status.Errorf(codes.Internal, "Could not create dir %q: %v", target, err)
-	}
-
-	klog.V(5).Infof("NodePublishVolume: mounting %s at %s with options %v", source, target, mountOptions)
-	if err := d.mounter.Mount(source, target, "efs", mountOptions); err != nil {
-		os.Remove(target)
-		return nil, status.Errorf(codes.Internal, "Could not mount %q at %q: %v", source, target, err)
-	}
-	klog.V(5).Infof("NodePublishVolume: %s was mounted", target)
-
-	//Increment volume Id counter
-	if d.volMetricsOptIn {
-		if value, ok := volumeIdCounter[req.GetVolumeId()]; ok {
-			volumeIdCounter[req.GetVolumeId()] = value + 1
-		} else {
-			volumeIdCounter[req.GetVolumeId()] = 1
-		}
+	if err := d.mountPolicy.check(volCap.GetMount().GetMountFlags(), mountOptions); err != nil {
+		return nil, err
 	}
-
-	return &csi.NodePublishVolumeResponse{}, nil
+	return &volumeMount{source: source, options: mountOptions, fileSystemId: fsid, accessPointId: apid}, nil
 }
 
 func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
@@ -225,6 +572,12 @@ func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublish
 		return nil, status.Error(codes.InvalidArgument, "Target path not provided")
 	}
 
+	done, err := d.inFlight.tryLock(req.GetVolumeId(), target)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	// Check if target directory is a mount point. GetDeviceNameFromMount
 	// given a mnt point, finds the device from /proc/mounts
 	// returns the device name, reference count, and error code
@@ -239,30 +592,18 @@ func (d *Driver) NodeUnpublishVolume(ctx context.Context, req *csi.NodeUnpublish
 	// reply 0 OK.
 	if refCount == 0 {
 		klog.V(5).Infof("NodeUnpublishVolume: %s target not mounted", target)
+		d.unpublished(req.GetVolumeId(), target)
 		return &csi.NodeUnpublishVolumeResponse{}, nil
 	}
 
 	klog.V(5).Infof("NodeUnpublishVolume: unmounting %s", target)
-	err = d.mounter.Unmount(target)
+	err = d.unmount(ctx, req.GetVolumeId(), target)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Could not unmount %q: %v", target, err)
 	}
 	klog.V(5).Infof("NodeUnpublishVolume: %s unmounted", target)
 
-	//TODO: If `du` is running on a volume, unmount waits for it to complete. We should stop `du` on unmount in the future for NodeUnpublish
-	//Decrement Volume ID counter and evict cache if counter is 0.
-	if d.volMetricsOptIn {
-		if value, ok := volumeIdCounter[req.GetVolumeId()]; ok {
-			value -= 1
-			if value < 1 {
-				klog.V(4).Infof("Evicting vol ID: %v, vol path : %v from cache", req.VolumeId, target)
-				d.volStatter.removeFromCache(req.VolumeId)
-				delete(volumeIdCounter, req.GetVolumeId())
-			} else {
-				volumeIdCounter[req.GetVolumeId()] = value
-			}
-		}
-	}
+	d.unpublished(req.GetVolumeId(), target)
 
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
@@ -280,6 +621,19 @@ func (d *Driver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeS
 		return nil, status.Error(codes.InvalidArgument, "Volume Path not provided")
 	}
 
+	// The stat of a hung mount would block, so the usage of abnormal mounts is only read from the cache.
+	condition := d.mountMonitor.condition(target)
+	if condition != nil && condition.Abnormal {
+		usage := []*csi.VolumeUsage{{Unit: csi.VolumeUsage_UNKNOWN}}
+		if cached, ok := d.volStatter.retrieveFromCache(volId); ok {
+			usage = cached.volUsage
+		}
+		return &csi.NodeGetVolumeStatsResponse{
+			Usage:           usage,
+			VolumeCondition: condition,
+		}, nil
+	}
+
 	_, err := os.Stat(target)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -296,7 +650,8 @@ func (d *Driver) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeS
 	}
 
 	return &csi.NodeGetVolumeStatsResponse{
-		Usage: volMetrics.volUsage,
+		Usage:           volMetrics.volUsage,
+		VolumeCondition: condition,
 	}, nil
 }
 
@@ -415,8 +770,10 @@ func parseVolumeId(volumeId string) (fsid, subpath, apid string, err error) {
 	}
 
 	tokens := strings.Split(volumeId, ":")
-	if len(tokens) > 3 {
-		err = status.Errorf(codes.InvalidArgument, "volume ID '%s' is invalid: Expected at most three fields separated by ':'", volumeId)
+	// The fourth field names the consumer of a shared access point, see sharedAccessPointConsumer, or marks a
+	// directory provisioned in efs-dir mode, see isProvisionedDirectory.
+	if len(tokens) > 4 || (len(tokens) == 4 && tokens[2] == "" && !isProvisionedDirectory(volumeId)) {
+		err = status.Errorf(codes.InvalidArgument, "volume ID '%s' is invalid: Expected at most four fields separated by ':', the fourth only with an access point or %v", volumeId, DirectoryMode)
 		return
 	}
 
@@ -429,7 +786,7 @@ func parseVolumeId(volumeId string) (fsid, subpath, apid string, err error) {
 	}
 
 	// Do we have an access point ID?
-	if len(tokens) == 3 && tokens[2] != "" {
+	if len(tokens) >= 3 && tokens[2] != "" {
 		apid = tokens[2]
 		if !isValidAccessPointId(apid) {
 			err = status.Errorf(codes.InvalidArgument, "volume ID '%s' has an invalid access point ID '%s': Expected it to be of the form 'fsap-...'", volumeId, apid)
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -30,12 +31,14 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
 
 const (
-	volumeId   = "fs-abc123"
-	targetPath = "/target/path"
+	volumeId    = "fs-abc123"
+	targetPath  = "/target/path"
+	stagingPath = "/staging/path"
 )
 
 type errtyp struct {
@@ -84,6 +87,264 @@ func testResult(t *testing.T, funcName string, ret interface{}, err error, expec
 	}
 }
 
+func TestNodeStageVolume(t *testing.T) {
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+
+	testCases := []struct {
+		name                string
+		req                 *csi.NodeStageVolumeRequest
+		getDeviceNameReturn []interface{}
+		expectMakeDir       bool
+		mountArgs           []interface{}
+		mountReturn         error
+		expectError         errtyp
+	}{
+		{
+			name: "success: normal",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{"", 0, nil},
+			expectMakeDir:       true,
+			mountArgs:           []interface{}{volumeId + ":/", stagingPath, "efs", []string{"tls"}},
+		},
+		{
+			name: "success: access point with mount flags",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId: volumeId + "::fsap-abcd1234",
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"iam"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{"", 0, nil},
+			expectMakeDir:       true,
+			mountArgs:           []interface{}{volumeId + ":/", stagingPath, "efs", []string{"accesspoint=fsap-abcd1234", "tls", "iam"}},
+		},
+		{
+			name: "success: already staged",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{volumeId + ":/", 1, nil},
+		},
+		{
+			name: "fail: missing volume ID",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+			},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Volume ID not provided",
+			},
+		},
+		{
+			name: "fail: missing staging target path",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:         volumeId,
+				VolumeCapability: stdVolCap,
+			},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Staging target path not provided",
+			},
+		},
+		{
+			name: "fail: missing volume capability",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				StagingTargetPath: stagingPath,
+			},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Volume capability not provided",
+			},
+		},
+		{
+			name: "fail: unsupported volume context",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				VolumeContext:     map[string]string{"asdf": "qwer"},
+			},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Volume context property asdf not supported.",
+			},
+		},
+		{
+			name: "fail: mounter failed to GetDeviceName",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{"", 0, fmt.Errorf("GetDeviceName failed")},
+			expectError: errtyp{
+				code:    "Internal",
+				message: "failed to check if volume is staged: GetDeviceName failed",
+			},
+		},
+		{
+			name: "fail: mounter failed to Mount",
+			req: &csi.NodeStageVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{"", 0, nil},
+			expectMakeDir:       true,
+			mountArgs:           []interface{}{volumeId + ":/", stagingPath, "efs", []string{"tls"}},
+			mountReturn:         fmt.Errorf("failed to Mount"),
+			expectError: errtyp{
+				code:    "Internal",
+				message: `Could not mount "fs-abc123:/" at "/staging/path": failed to Mount`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
+
+			if tc.getDeviceNameReturn != nil {
+				mockMounter.EXPECT().
+					GetDeviceName(stagingPath).
+					Return(tc.getDeviceNameReturn[0], tc.getDeviceNameReturn[1], tc.getDeviceNameReturn[2])
+			}
+			if tc.expectMakeDir {
+				mockMounter.EXPECT().MakeDir(stagingPath).Return(nil)
+			}
+			if tc.mountArgs != nil {
+				mockMounter.EXPECT().Mount(tc.mountArgs[0], tc.mountArgs[1], tc.mountArgs[2], tc.mountArgs[3]).Return(tc.mountReturn)
+			}
+
+			ret, err := driver.NodeStageVolume(ctx, tc.req)
+			testResult(t, "NodeStageVolume", ret, err, tc.expectError)
+		})
+	}
+}
+
+func TestNodeStageVolumeMountFailureEvent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
+	eventRecorder, recorder := newFakeVolumeEventRecorder(&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv"}})
+	driver.eventRecorder = eventRecorder
+
+	mockMounter.EXPECT().GetDeviceName(stagingPath).Return("", 0, nil)
+	mockMounter.EXPECT().MakeDir(stagingPath).Return(nil)
+	mockMounter.EXPECT().Mount(volumeId+":/", stagingPath, "efs", []string{"tls"}).Return(fmt.Errorf("Connection to the mount target timed out"))
+
+	// NodeStageVolume is not given the pods the volume is mounted for, so the failure is recorded on the PV.
+	_, err := driver.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{
+		VolumeId: volumeId,
+		VolumeCapability: &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER},
+		},
+		StagingTargetPath: stagingPath,
+		VolumeContext:     map[string]string{VolumeContextPvName: "pv"},
+	})
+	if err == nil {
+		t.Fatalf("NodeStageVolume is not failed")
+	}
+	expectEvent(t, recorder, ReasonMountTargetUnreachable)
+}
+
+func TestNodeUnstageVolume(t *testing.T) {
+	testCases := []struct {
+		name                string
+		req                 *csi.NodeUnstageVolumeRequest
+		getDeviceNameReturn []interface{}
+		expectUnmount       bool
+		unmountReturn       error
+		expectError         errtyp
+	}{
+		{
+			name: "success: normal",
+			req: &csi.NodeUnstageVolumeRequest{
+				VolumeId:          volumeId,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{volumeId + ":/", 1, nil},
+			expectUnmount:       true,
+		},
+		{
+			name: "success: not staged",
+			req: &csi.NodeUnstageVolumeRequest{
+				VolumeId:          volumeId,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{"", 0, nil},
+		},
+		{
+			name: "fail: missing staging target path",
+			req: &csi.NodeUnstageVolumeRequest{
+				VolumeId: volumeId,
+			},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Staging target path not provided",
+			},
+		},
+		{
+			name: "fail: mounter failed to umount",
+			req: &csi.NodeUnstageVolumeRequest{
+				VolumeId:          volumeId,
+				StagingTargetPath: stagingPath,
+			},
+			getDeviceNameReturn: []interface{}{volumeId + ":/", 1, nil},
+			expectUnmount:       true,
+			unmountReturn:       fmt.Errorf("Unmount failed"),
+			expectError: errtyp{
+				code:    "Internal",
+				message: `Could not unmount "/staging/path": Unmount failed`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
+
+			if tc.getDeviceNameReturn != nil {
+				mockMounter.EXPECT().
+					GetDeviceName(stagingPath).
+					Return(tc.getDeviceNameReturn[0], tc.getDeviceNameReturn[1], tc.getDeviceNameReturn[2])
+			}
+			if tc.expectUnmount {
+				mockMounter.EXPECT().Unmount(stagingPath).Return(tc.unmountReturn)
+			}
+
+			ret, err := driver.NodeUnstageVolume(ctx, tc.req)
+			testResult(t, "NodeUnstageVolume", ret, err, tc.expectError)
+		})
+	}
+}
+
 func TestNodePublishVolume(t *testing.T) {
 
 	var (
@@ -105,7 +366,14 @@ func TestNodePublishVolume(t *testing.T) {
 		mountArgs       []interface{}
 		mountSuccess    bool
 		volMetricsOptIn bool
-		expectError     errtyp
+		mountPolicy     *mountPolicy
+		stagedRefCount  int
+		// targetMountedDevice is the device already mounted at the target.
+		targetMountedDevice string
+		// mountInfo and tunnelStateFiles tell which volume is mounted at the target.
+		mountInfo        string
+		tunnelStateFiles map[string]string
+		expectError      errtyp
 	}{
 		{
 			name: "success: normal",
@@ -119,6 +387,25 @@ func TestNodePublishVolume(t *testing.T) {
 			mountSuccess:    true,
 			volMetricsOptIn: true,
 		},
+		{
+			name: "success: pod info in volume context",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+				VolumeContext: map[string]string{
+					PodName:               "pod",
+					PodNamespace:          "default",
+					PodUID:                "c2f5f8fb-6fe4-4b1c-a4c4-2b0a1a0b2b2b",
+					PodServiceAccountName: "default",
+					PodEphemeral:          "false",
+				},
+			},
+			expectMakeDir:   true,
+			mountArgs:       []interface{}{volumeId + ":/", targetPath, "efs", []string{"tls"}},
+			mountSuccess:    true,
+			volMetricsOptIn: true,
+		},
 		{
 			name: "success: empty path",
 			req: &csi.NodePublishVolumeRequest{
@@ -226,6 +513,17 @@ func TestNodePublishVolume(t *testing.T) {
 			mountArgs:     []interface{}{volumeId + ":a/b", targetPath, "efs", []string{"tls"}},
 			mountSuccess:  true,
 		},
+		{
+			name: "success: directory provisioned in efs-dir mode",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + ":/a/b::efs-dir",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			expectMakeDir: true,
+			mountArgs:     []interface{}{volumeId + ":/a/b", targetPath, "efs", []string{"tls"}},
+			mountSuccess:  true,
+		},
 		{
 			name: "success: path in volume handle takes precedence",
 			req: &csi.NodePublishVolumeRequest{
@@ -260,6 +558,17 @@ func TestNodePublishVolume(t *testing.T) {
 			mountArgs:     []interface{}{volumeId + ":/a/b", targetPath, "efs", []string{"accesspoint=" + accessPointID, "tls"}},
 			mountSuccess:  true,
 		},
+		{
+			name: "success: shared access point in volume handle",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + "::" + accessPointID + ":pv-1",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			expectMakeDir: true,
+			mountArgs:     []interface{}{volumeId + ":/", targetPath, "efs", []string{"accesspoint=" + accessPointID, "tls"}},
+			mountSuccess:  true,
+		},
 		{
 			// TODO: Validate deprecation warning
 			name: "success: same access point in volume handle and mount options",
@@ -395,7 +704,20 @@ func TestNodePublishVolume(t *testing.T) {
 			expectMakeDir: false,
 			expectError: errtyp{
 				code:    "InvalidArgument",
-				message: "volume ID 'fs-abc123:/a/b/::four!' is invalid: Expected at most three fields separated by ':'",
+				message: "volume ID 'fs-abc123:/a/b/::four!' is invalid: Expected at most four fields separated by ':', the fourth only with an access point or efs-dir",
+			},
+		},
+		{
+			name: "fail: five fields in volume handle",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + "::" + accessPointID + ":pvc-123:five",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			expectMakeDir: false,
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "volume ID 'fs-abc123::" + accessPointID + ":pvc-123:five' is invalid: Expected at most four fields separated by ':', the fourth only with an access point or efs-dir",
 			},
 		},
 		{
@@ -589,14 +911,331 @@ func TestNodePublishVolume(t *testing.T) {
 				message: "Volume context property \"encryptInTransit\" must be a boolean value: strconv.ParseBool: parsing \"asdf\": invalid syntax",
 			},
 		},
+		{
+			name: "success: mount options allowed by mount policy",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId: volumeId,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"iam", "rsize=1048576"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				TargetPath: targetPath,
+			},
+			mountPolicy: &mountPolicy{
+				AllowedMountOptions: []string{"iam", "rsize"},
+				RequireTLS:          true,
+				RequireIAM:          true,
+				MaxRsize:            1048576,
+			},
+			expectMakeDir: true,
+			mountArgs:     []interface{}{volumeId + ":/", targetPath, "efs", []string{"tls", "iam", "rsize=1048576"}},
+			mountSuccess:  true,
+		},
+		{
+			name: "success: bind mount staged volume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+			},
+			stagedRefCount: 1,
+			expectMakeDir:  true,
+			mountArgs:      []interface{}{stagingPath, targetPath, "", []string{"bind"}},
+			mountSuccess:   true,
+		},
+		{
+			name: "success: read only bind mount of staged volume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId + "::" + accessPointID,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+				Readonly:          true,
+			},
+			stagedRefCount: 2,
+			expectMakeDir:  true,
+			mountArgs:      []interface{}{stagingPath, targetPath, "", []string{"bind", "ro"}},
+			mountSuccess:   true,
+		},
+		{
+			name: "fail: volume not staged",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+			},
+			expectError: errtyp{
+				code:    "FailedPrecondition",
+				message: `Volume fs-abc123 is not staged at "/staging/path"`,
+			},
+		},
+		{
+			name: "fail: mounter failed to bind mount staged volume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+			},
+			stagedRefCount: 1,
+			expectMakeDir:  true,
+			mountArgs:      []interface{}{stagingPath, targetPath, "", []string{"bind"}},
+			mountSuccess:   false,
+			expectError: errtyp{
+				code:    "Internal",
+				message: `Could not bind mount "/staging/path" at "/target/path": failed to Mount`,
+			},
+		},
+		{
+			name: "success: target already mounted",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: volumeId + ":/",
+		},
+		{
+			name: "success: target already mounted through TLS",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + ":/a/b",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: "127.0.0.1:/a/b",
+			tunnelStateFiles:    map[string]string{"fs-def456.target.path.20049": "{}", "fs-abc123.target.path.20050": "{}"},
+		},
+		{
+			name: "success: target already mounted through TLS with access point",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + "::" + accessPointID,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: "127.0.0.1:/",
+			tunnelStateFiles:    map[string]string{"fs-abc123.target.path.20049": `{"accessPoint": "` + accessPointID + `"}`},
+		},
+		{
+			name: "fail: target already mounted through TLS from another file system",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + ":/a/b",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: "127.0.0.1:/a/b",
+			tunnelStateFiles:    map[string]string{"fs-def456.target.path.20049": "{}", "fs-abc123.other.path.20050": "{}"},
+			expectError: errtyp{
+				code:    "FailedPrecondition",
+				message: `Target "/target/path" is already mounted from "127.0.0.1:/a/b", not "fs-abc123:/a/b"`,
+			},
+		},
+		{
+			name: "fail: target already mounted through TLS from another access point",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + "::" + accessPointID,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: "127.0.0.1:/",
+			tunnelStateFiles:    map[string]string{"fs-abc123.target.path.20049": `{"accessPoint": "fsap-other"}`},
+			expectError: errtyp{
+				code:    "FailedPrecondition",
+				message: `Target "/target/path" is already mounted from "127.0.0.1:/", not "fs-abc123:/"`,
+			},
+		},
+		{
+			name: "success: target already bind mounted from staged volume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+			},
+			stagedRefCount:      2,
+			targetMountedDevice: "127.0.0.1:/",
+			mountInfo: "36 25 0:52 / /staging/path rw,relatime shared:1 - nfs4 127.0.0.1:/ rw,vers=4.1,port=20049\n" +
+				"37 25 0:52 / /target/path rw,relatime shared:1 - nfs4 127.0.0.1:/ rw,vers=4.1,port=20049\n",
+		},
+		{
+			name: "fail: target already bind mounted from another staged volume",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:          volumeId,
+				VolumeCapability:  stdVolCap,
+				StagingTargetPath: stagingPath,
+				TargetPath:        targetPath,
+			},
+			stagedRefCount:      2,
+			targetMountedDevice: "127.0.0.1:/",
+			mountInfo: "36 25 0:52 / /staging/path rw,relatime shared:1 - nfs4 127.0.0.1:/ rw,vers=4.1,port=20049\n" +
+				"37 25 0:53 / /target/path rw,relatime shared:2 - nfs4 127.0.0.1:/ rw,vers=4.1,port=20050\n",
+			expectError: errtyp{
+				code:    "FailedPrecondition",
+				message: `Target "/target/path" is already mounted from "127.0.0.1:/", not "127.0.0.1:/"`,
+			},
+		},
+		{
+			name: "fail: target already mounted from another source",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId + ":/a",
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			targetMountedDevice: "fs-def456.efs.us-east-1.amazonaws.com:/b",
+			expectError: errtyp{
+				code:    "FailedPrecondition",
+				message: `Target "/target/path" is already mounted from "fs-def456.efs.us-east-1.amazonaws.com:/b", not "fs-abc123:/a"`,
+			},
+		},
+		{
+			name: "fail: mount option not allowed by mount policy",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId: volumeId,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"noresvport"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				TargetPath: targetPath,
+			},
+			mountPolicy: &mountPolicy{AllowedMountOptions: []string{"iam"}},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule allowedMountOptions does not allow mount option \"noresvport\"",
+			},
+		},
+		{
+			name: "fail: mount option denied by mount policy",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId: volumeId,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"awsprofile=other"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				TargetPath: targetPath,
+			},
+			mountPolicy: &mountPolicy{DeniedMountOptions: []string{"awsprofile"}},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule deniedMountOptions denies mount option \"awsprofile\"",
+			},
+		},
+		{
+			name: "fail: encryptInTransit false with mount policy requiring TLS",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+				VolumeContext:    map[string]string{"encryptInTransit": "false"},
+			},
+			mountPolicy: &mountPolicy{RequireTLS: true},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule requireTLS does not allow encryptInTransit to be false",
+			},
+		},
+		{
+			name: "fail: iam missing with mount policy requiring IAM",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId:         volumeId,
+				VolumeCapability: stdVolCap,
+				TargetPath:       targetPath,
+			},
+			mountPolicy: &mountPolicy{RequireIAM: true},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule requireIAM requires the iam mount option",
+			},
+		},
+		{
+			name: "fail: wsize above mount policy limit",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId: volumeId,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"wsize=2097152"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				TargetPath: targetPath,
+			},
+			mountPolicy: &mountPolicy{MaxWsize: 1048576},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule maxWsize limits wsize to 1048576, got 2097152",
+			},
+		},
+		{
+			name: "fail: uppercase rsize above mount policy limit",
+			req: &csi.NodePublishVolumeRequest{
+				VolumeId: volumeId,
+				VolumeCapability: &csi.VolumeCapability{
+					AccessType: &csi.VolumeCapability_Mount{
+						Mount: &csi.VolumeCapability_MountVolume{
+							MountFlags: []string{"RSIZE=2097152"},
+						},
+					},
+					AccessMode: &csi.VolumeCapability_AccessMode{
+						Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+					},
+				},
+				TargetPath: targetPath,
+			},
+			mountPolicy: &mountPolicy{MaxRsize: 1048576},
+			expectError: errtyp{
+				code:    "InvalidArgument",
+				message: "Mount policy rule maxRsize limits rsize to 1048576, got 2097152",
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
-			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(), tc.volMetricsOptIn)
+			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), tc.volMetricsOptIn)
+			driver.mountPolicy = tc.mountPolicy
+			dir := t.TempDir()
+			driver.mountInfoPath = filepath.Join(dir, "mountinfo")
+			createFile(t, dir, "mountinfo", tc.mountInfo)
+			driver.tunnelStateDir = filepath.Join(dir, "efs")
+			if err := os.Mkdir(driver.tunnelStateDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for name, content := range tc.tunnelStateFiles {
+				createFile(t, driver.tunnelStateDir, name, content)
+			}
 
+			if tc.req.StagingTargetPath != "" && tc.expectError.code != "InvalidArgument" {
+				mockMounter.EXPECT().GetDeviceName(stagingPath).Return("127.0.0.1:/", tc.stagedRefCount, nil)
+			}
+			if tc.targetMountedDevice != "" {
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return(tc.targetMountedDevice, 1, nil)
+			} else if tc.expectMakeDir {
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 0, nil)
+			}
 			if tc.expectMakeDir {
 				var err error
 				// If not expecting mount, it's because mkdir errored
@@ -723,7 +1362,7 @@ func TestNodeUnpublishVolume(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
-			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(), true)
+			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
 
 			if tc.expectGetDeviceName {
 				mockMounter.EXPECT().
@@ -745,6 +1384,172 @@ func TestNodeUnpublishVolume(t *testing.T) {
 	}
 }
 
+func TestNodeOperationsInFlight(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	_, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
+
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+	done, err := driver.inFlight.tryLock(volumeId, targetPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+	stagingDone, err := driver.inFlight.tryLock(volumeId, stagingPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stagingDone()
+
+	expectError := errtyp{
+		code:    "Aborted",
+		message: `An operation on volume fs-abc123 at "/target/path" is already in progress`,
+	}
+	publishRet, err := driver.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{VolumeId: volumeId, VolumeCapability: stdVolCap, TargetPath: targetPath})
+	testResult(t, "NodePublishVolume", publishRet, err, expectError)
+	unpublishRet, err := driver.NodeUnpublishVolume(ctx, &csi.NodeUnpublishVolumeRequest{VolumeId: volumeId, TargetPath: targetPath})
+	testResult(t, "NodeUnpublishVolume", unpublishRet, err, expectError)
+
+	expectError.message = `An operation on volume fs-abc123 at "/staging/path" is already in progress`
+	stageRet, err := driver.NodeStageVolume(ctx, &csi.NodeStageVolumeRequest{VolumeId: volumeId, VolumeCapability: stdVolCap, StagingTargetPath: stagingPath})
+	testResult(t, "NodeStageVolume", stageRet, err, expectError)
+	unstageRet, err := driver.NodeUnstageVolume(ctx, &csi.NodeUnstageVolumeRequest{VolumeId: volumeId, StagingTargetPath: stagingPath})
+	testResult(t, "NodeUnstageVolume", unstageRet, err, expectError)
+}
+
+func TestNodeMountTimeouts(t *testing.T) {
+	stdVolCap := &csi.VolumeCapability{
+		AccessType: &csi.VolumeCapability_Mount{
+			Mount: &csi.VolumeCapability_MountVolume{},
+		},
+		AccessMode: &csi.VolumeCapability_AccessMode{
+			Mode: csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
+		},
+	}
+	testCases := []struct {
+		name             string
+		unpublish        bool
+		noUnmountTimeout bool
+		expectForce      bool
+		unmountForce     error
+		expectLazy       bool
+		unmountLazy      error
+		expectError      errtyp
+	}{
+		{
+			name: "fail: mount times out",
+			expectError: errtyp{
+				code:    "DeadlineExceeded",
+				message: `Mounting "fs-abc123:/" at "/target/path" did not finish within 10ms or the deadline of the request`,
+			},
+		},
+		{
+			name:        "success: unmount times out and is forced",
+			unpublish:   true,
+			expectForce: true,
+		},
+		{
+			name:         "success: forced unmount fails and is lazy",
+			unpublish:    true,
+			expectForce:  true,
+			unmountForce: fmt.Errorf("device is busy"),
+			expectLazy:   true,
+		},
+		{
+			name:         "fail: lazy unmount fails",
+			unpublish:    true,
+			expectForce:  true,
+			unmountForce: fmt.Errorf("device is busy"),
+			expectLazy:   true,
+			unmountLazy:  fmt.Errorf("not mounted"),
+			expectError: errtyp{
+				code:    "Internal",
+				message: `Could not unmount "/target/path": not mounted`,
+			},
+		},
+		{
+			name:             "fail: unmount without timeout is not forced once the request is done",
+			unpublish:        true,
+			noUnmountTimeout: true,
+			expectError: errtyp{
+				code:    "Internal",
+				message: `Could not unmount "/target/path": context deadline exceeded`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMounter, driver, ctx := setup(mockCtrl, NewVolStatter(1), true)
+			driver.mountTimeout = 10 * time.Millisecond
+			driver.unmountTimeout = 10 * time.Millisecond
+			if tc.noUnmountTimeout {
+				driver.unmountTimeout = 0
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+				defer cancel()
+			}
+			// The first mount or unmount does not return until the test ends.
+			hung := make(chan struct{})
+			defer close(hung)
+
+			var err error
+			var ret interface{}
+			if tc.unpublish {
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return(volumeId+":/", 1, nil)
+				mockMounter.EXPECT().Unmount(targetPath).Do(func(string) { <-hung }).Return(nil)
+				if tc.expectForce {
+					mockMounter.EXPECT().UnmountForce(targetPath).Return(tc.unmountForce)
+				}
+				if tc.expectLazy {
+					mockMounter.EXPECT().UnmountLazy(targetPath).Return(tc.unmountLazy)
+				}
+				ret, err = driver.NodeUnpublishVolume(ctx, &csi.NodeUnpublishVolumeRequest{VolumeId: volumeId, TargetPath: targetPath})
+			} else {
+				mockMounter.EXPECT().GetDeviceName(targetPath).Return("", 0, nil)
+				mockMounter.EXPECT().MakeDir(targetPath).Return(nil)
+				mockMounter.EXPECT().Mount(volumeId+":/", targetPath, "efs", []string{"tls"}).Do(func(string, string, string, []string) { <-hung }).Return(nil)
+				ret, err = driver.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{VolumeId: volumeId, VolumeCapability: stdVolCap, TargetPath: targetPath})
+			}
+			testResult(t, tc.name, ret, err, tc.expectError)
+
+			// The target stays locked while the operation that timed out still runs.
+			if _, err := driver.inFlight.tryLock(volumeId, targetPath); err == nil {
+				t.Fatalf("Expected target to be locked")
+			}
+		})
+	}
+}
+
+func TestIsMountedFrom(t *testing.T) {
+	testCases := []struct {
+		device   string
+		source   string
+		expected bool
+	}{
+		{"fs-abc123:/", "fs-abc123:/", true},
+		{"127.0.0.1:/a/b", "fs-abc123:/a/b/", true},
+		{"fs-abc123.efs.us-east-1.amazonaws.com:/a", "fs-abc123:/a", true},
+		{"/staging/path", "/staging/path", true},
+		{"127.0.0.1:/a", "fs-abc123:/b", false},
+		{"fs-def456.efs.us-east-1.amazonaws.com:/", "fs-abc123:/", false},
+		{"tmpfs", "fs-abc123:/", false},
+	}
+	for _, tc := range testCases {
+		if actual := isMountedFrom(tc.device, tc.source); actual != tc.expected {
+			t.Errorf("Expected isMountedFrom(%q, %q) to be %v, got %v", tc.device, tc.source, tc.expected, actual)
+		}
+	}
+}
+
 func TestNodeGetVolumeStats(t *testing.T) {
 	var (
 		validPath   = "/tmp/target"
@@ -771,15 +1576,41 @@ func TestNodeGetVolumeStats(t *testing.T) {
 		name             string
 		req              *csi.NodeGetVolumeStatsRequest
 		updateCache      bool
+		statfsErr        error
+		monitoredMount   *monitoredMount
 		expectError      errtyp
 		expectedResponse *csi.NodeGetVolumeStatsResponse
 	}{
 		{
-			name: "success: volume unknown",
+			name: "success: volume unknown reports the usage of its file system",
 			req: &csi.NodeGetVolumeStatsRequest{
 				VolumeId:   volumeId,
 				VolumePath: validPath,
 			},
+			expectedResponse: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{
+						Unit:      csi.VolumeUsage_BYTES,
+						Available: 1,
+						Total:     2,
+						Used:      1,
+					},
+					{
+						Unit:      csi.VolumeUsage_INODES,
+						Available: 3,
+						Total:     4,
+						Used:      1,
+					},
+				},
+			},
+		},
+		{
+			name: "success: volume unknown and statfs failed",
+			req: &csi.NodeGetVolumeStatsRequest{
+				VolumeId:   volumeId,
+				VolumePath: validPath,
+			},
+			statfsErr: fmt.Errorf("statfs failed"),
 			expectedResponse: &csi.NodeGetVolumeStatsResponse{
 				Usage: []*csi.VolumeUsage{
 					{
@@ -806,6 +1637,62 @@ func TestNodeGetVolumeStats(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "success: healthy mount",
+			req: &csi.NodeGetVolumeStatsRequest{
+				VolumeId:   volumeId,
+				VolumePath: validPath,
+			},
+			updateCache:    true,
+			monitoredMount: &monitoredMount{volumeId: volumeId},
+			expectedResponse: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{
+						Unit:      csi.VolumeUsage_BYTES,
+						Available: 1,
+						Total:     2,
+						Used:      1,
+					},
+				},
+				VolumeCondition: &csi.VolumeCondition{Abnormal: false, Message: "Volume is mounted and responding"},
+			},
+		},
+		{
+			name: "success: abnormal mount with cached usage",
+			req: &csi.NodeGetVolumeStatsRequest{
+				VolumeId:   volumeId,
+				VolumePath: validPath,
+			},
+			updateCache:    true,
+			monitoredMount: &monitoredMount{volumeId: volumeId, abnormal: true, message: "Mount is stale: stale file handle"},
+			expectedResponse: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{
+						Unit:      csi.VolumeUsage_BYTES,
+						Available: 1,
+						Total:     2,
+						Used:      1,
+					},
+				},
+				VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: "Mount is stale: stale file handle"},
+			},
+		},
+		{
+			name: "success: abnormal mount is not stat",
+			req: &csi.NodeGetVolumeStatsRequest{
+				VolumeId:   volumeId,
+				VolumePath: invalidPath,
+			},
+			monitoredMount: &monitoredMount{volumeId: volumeId, abnormal: true, message: "Mount is hung: stat did not return within 10s"},
+			expectedResponse: &csi.NodeGetVolumeStatsResponse{
+				Usage: []*csi.VolumeUsage{
+					{
+						Unit: csi.VolumeUsage_UNKNOWN,
+					},
+				},
+				VolumeCondition: &csi.VolumeCondition{Abnormal: true, Message: "Mount is hung: stat did not return within 10s"},
+			},
+		},
 		{
 			name: "Fail: Path does not exist",
 			req: &csi.NodeGetVolumeStatsRequest{
@@ -850,7 +1737,14 @@ func TestNodeGetVolumeStats(t *testing.T) {
 			//setup
 			mockCtrl := gomock.NewController(t)
 			defer mockCtrl.Finish()
-			_, driver, ctx = setup(mockCtrl, NewVolStatter(), true)
+			volStatter := &VolStatterImpl{walkConcurrency: 1, statfs: func(path string) (fsStats, error) {
+				return fsStats{available: 1, capacity: 2, inodes: 4, inodesFree: 3}, tc.statfsErr
+			}}
+			_, driver, ctx = setup(mockCtrl, volStatter, true)
+			if tc.monitoredMount != nil {
+				driver.mountMonitor = newMountMonitor(time.Minute, 10*time.Second, false, 0, nil, &driver.inFlight, nil)
+				driver.mountMonitor.mounts[tc.req.VolumePath] = tc.monitoredMount
+			}
 
 			if tc.updateCache {
 				mu.Lock()
@@ -866,6 +1760,16 @@ func TestNodeGetVolumeStats(t *testing.T) {
 			if tc.expectedResponse != nil {
 				testResponse(t, tc.expectedResponse, ret)
 			}
+			// Wait for the usage computed in the background, which the next case would find in the cache.
+			for i := 0; i < 100; i++ {
+				mu.RLock()
+				_, running := volStatterJobTracker[volumeId]
+				mu.RUnlock()
+				if !running {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
 			mu.Lock()
 			delete(volUsageCache, volumeId)
 			mu.Unlock()
@@ -875,6 +1779,29 @@ func TestNodeGetVolumeStats(t *testing.T) {
 	os.RemoveAll(validPath)
 }
 
+func TestVolStatterDelaysFirstWalk(t *testing.T) {
+	defer func(j time.Duration) { jitter = j }(jitter)
+	jitter = time.Hour
+	dir := t.TempDir()
+	volStatter := &VolStatterImpl{walkConcurrency: 1, statfs: func(path string) (fsStats, error) {
+		return fsStats{available: 1, capacity: 3, inodes: 4, inodesFree: 3}, nil
+	}}
+
+	metrics, err := volStatter.computeVolumeMetrics(volumeId, dir, 1, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer volStatter.cancelComputation(volumeId)
+	// The file system usage is reported until the walk finishes, rather than an empty volume.
+	if used := metrics.volUsage[0].Used; used != 2 {
+		t.Fatalf("Expected 2 bytes used by the file system, got %d", used)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := volStatter.retrieveFromCache(volumeId); ok {
+		t.Fatalf("Expected the first walk to wait for its delay")
+	}
+}
+
 func testResponse(t *testing.T, expected, actual *csi.NodeGetVolumeStatsResponse) {
 	if !reflect.DeepEqual(expected, actual) {
 		t.Errorf("Expected: %v, Actual: %v", expected, actual)
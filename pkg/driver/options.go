@@ -0,0 +1,233 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// DriverOptionsVersion is the version of the driver config file.
+const DriverOptionsVersion = "v1"
+
+// DriverOptions configures the driver. It is read from the driver config file, with the command line flags
+// overriding it, or built directly by tests.
+type DriverOptions struct {
+	// Version is the version of the config file, DriverOptionsVersion.
+	Version string `json:"version"`
+	// Endpoint is the CSI endpoint the driver listens on.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Mode is ControllerMode, NodeMode or AllMode.
+	Mode string `json:"mode,omitempty"`
+	// FeatureGates enable or disable the features by name, see defaultFeatureGates.
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	Controller ControllerOptions `json:"controller,omitempty"`
+	Node       NodeOptions       `json:"node,omitempty"`
+	Metrics    MetricsOptions    `json:"metrics,omitempty"`
+	EfsUtils   EfsUtilsOptions   `json:"efsUtils,omitempty"`
+}
+
+// ControllerOptions configures the controller service and its reconcilers.
+type ControllerOptions struct {
+	// Tags are space separated key:value pairs added as tags to the EFS resources.
+	Tags string `json:"tags,omitempty"`
+	// ClusterId identifies the cluster among the clusters provisioning on the same file systems.
+	ClusterId                string `json:"clusterId,omitempty"`
+	DeleteAccessPointRootDir bool   `json:"deleteAccessPointRootDir,omitempty"`
+	// ArchiveDeletedDirectories renames the directories of deleted efs-dir volumes instead of deleting them.
+	ArchiveDeletedDirectories bool `json:"archiveDeletedDirectories,omitempty"`
+	// ForeignAccessPointDeletion and ProtectedAccessPointDeletion are AccessPointDeletionDetach or
+	// AccessPointDeletionRefuse.
+	ForeignAccessPointDeletion   string `json:"foreignAccessPointDeletion,omitempty"`
+	ProtectedAccessPointDeletion string `json:"protectedAccessPointDeletion,omitempty"`
+	ProvisioningPolicyFile       string `json:"provisioningPolicyFile,omitempty"`
+	RoleMappingFile              string `json:"roleMappingFile,omitempty"`
+	// EfsAPIHealthCheck includes EFS API access in the readiness checks.
+	EfsAPIHealthCheck bool `json:"efsAPIHealthCheck,omitempty"`
+
+	LeaderElectionNamespace              string          `json:"leaderElectionNamespace,omitempty"`
+	OrphanedAccessPointReconcileInterval metav1.Duration `json:"orphanedAccessPointReconcileInterval,omitempty"`
+	OrphanedAccessPointGracePeriod       metav1.Duration `json:"orphanedAccessPointGracePeriod,omitempty"`
+	DeleteOrphanedAccessPoints           bool            `json:"deleteOrphanedAccessPoints,omitempty"`
+	OrphanedRootDirScanInterval          metav1.Duration `json:"orphanedRootDirScanInterval,omitempty"`
+	OrphanedRootDirScanDryRun            bool            `json:"orphanedRootDirScanDryRun"`
+	OrphanedRootDirGracePeriod           metav1.Duration `json:"orphanedRootDirGracePeriod,omitempty"`
+}
+
+// NodeOptions configures the node service.
+type NodeOptions struct {
+	VolMetricsOptIn bool `json:"volMetricsOptIn,omitempty"`
+	// VolMetricsRefreshPeriod is in minutes.
+	VolMetricsRefreshPeriod float64 `json:"volMetricsRefreshPeriod,omitempty"`
+	VolMetricsFsRateLimit   int     `json:"volMetricsFsRateLimit,omitempty"`
+	// VolMetricsWalkConcurrency is the number of directories of a volume read at once to compute its usage.
+	VolMetricsWalkConcurrency int    `json:"volMetricsWalkConcurrency,omitempty"`
+	MountPolicyFile           string `json:"mountPolicyFile,omitempty"`
+	// VolumeStateFile persists the volumes published on the node across restarts of the node plugin. They are
+	// only tracked in memory if empty.
+	VolumeStateFile string `json:"volumeStateFile,omitempty"`
+	// KubeletDir is the root directory of kubelet, under which the published volumes are found again when there is
+	// no VolumeStateFile to restore them from.
+	KubeletDir string `json:"kubeletDir,omitempty"`
+	// MountCheckInterval is the interval at which the mounts are checked, disabled if 0. The mounts whose stat fails
+	// or does not return within MountCheckTimeout are abnormal, and are mounted again if RemountStaleVolumes is set.
+	MountCheckInterval  metav1.Duration `json:"mountCheckInterval,omitempty"`
+	MountCheckTimeout   metav1.Duration `json:"mountCheckTimeout,omitempty"`
+	RemountStaleVolumes bool            `json:"remountStaleVolumes,omitempty"`
+	// MountTimeout and UnmountTimeout bound the mounts and unmounts, on top of the deadline of the request, without
+	// limit if 0. An unmount that times out is forced, then made lazy, which only happens with an UnmountTimeout.
+	MountTimeout   metav1.Duration `json:"mountTimeout,omitempty"`
+	UnmountTimeout metav1.Duration `json:"unmountTimeout,omitempty"`
+}
+
+// MetricsOptions configures the HTTP endpoints of the driver, which are disabled if their address is empty.
+type MetricsOptions struct {
+	// Address serves /metrics.
+	Address string `json:"address,omitempty"`
+	// HealthAddress serves /healthz and /readyz. It may be the same as Address.
+	HealthAddress string `json:"healthAddress,omitempty"`
+}
+
+// EfsUtilsOptions configures the files of efs-utils.
+type EfsUtilsOptions struct {
+	// ConfigDirPath and LegacyConfigDirPath are the candidates for the efs-utils config directory, see InitConfigDir.
+	ConfigDirPath       string `json:"configDirPath,omitempty"`
+	LegacyConfigDirPath string `json:"legacyConfigDirPath,omitempty"`
+	// ConfigPath is the directory mount.efs reads its config from, linked to the chosen config directory. It is
+	// not configurable, but tests may change it.
+	ConfigPath      string `json:"-"`
+	StaticFilesPath string `json:"staticFilesPath,omitempty"`
+	SettingsFile    string `json:"settingsFile,omitempty"`
+}
+
+// DefaultDriverOptions returns the options used when neither the config file nor the flags set them.
+func DefaultDriverOptions() *DriverOptions {
+	return &DriverOptions{
+		Version:  DriverOptionsVersion,
+		Endpoint: "unix://tmp/csi.sock",
+		Mode:     AllMode,
+		Controller: ControllerOptions{
+			ForeignAccessPointDeletion:     AccessPointDeletionDetach,
+			ProtectedAccessPointDeletion:   AccessPointDeletionRefuse,
+			LeaderElectionNamespace:        "kube-system",
+			OrphanedAccessPointGracePeriod: metav1.Duration{Duration: 24 * time.Hour},
+			OrphanedRootDirScanDryRun:      true,
+			OrphanedRootDirGracePeriod:     metav1.Duration{Duration: 24 * time.Hour},
+		},
+		Node: NodeOptions{
+			VolMetricsRefreshPeriod:   240,
+			VolMetricsFsRateLimit:     5,
+			VolMetricsWalkConcurrency: 8,
+			KubeletDir:                "/var/lib/kubelet",
+			MountCheckTimeout:         metav1.Duration{Duration: 10 * time.Second},
+		},
+		EfsUtils: EfsUtilsOptions{
+			ConfigDirPath:       "/var/amazon/efs",
+			LegacyConfigDirPath: "/etc/amazon/efs-legacy",
+			ConfigPath:          "/etc/amazon/efs",
+			StaticFilesPath:     "/etc/amazon/efs-static-files/",
+		},
+	}
+}
+
+// LoadDriverOptions reads the options from the driver config file. The options it does not set keep their
+// defaults.
+func LoadDriverOptions(file string) (*DriverOptions, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read driver config: %v", err)
+	}
+	opts := DefaultDriverOptions()
+	opts.Version = ""
+	if err := yaml.UnmarshalStrict(data, opts); err != nil {
+		return nil, fmt.Errorf("failed to parse driver config %s: %v", file, err)
+	}
+	if opts.Version != DriverOptionsVersion {
+		return nil, fmt.Errorf("unsupported version %q of driver config %s, expected %q", opts.Version, file, DriverOptionsVersion)
+	}
+	return opts, nil
+}
+
+// Validate returns all the invalid options, by their path in the config file.
+func (o *DriverOptions) Validate() error {
+	var errs []error
+	invalid := func(field string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if o.Endpoint == "" {
+		invalid("endpoint", "must not be empty")
+	}
+	if o.Mode != ControllerMode && o.Mode != NodeMode && o.Mode != AllMode {
+		invalid("mode", "got %q, expected %q, %q or %q", o.Mode, ControllerMode, NodeMode, AllMode)
+	}
+	if err := ValidateFeatureGates(o.FeatureGates); err != nil {
+		invalid("featureGates", "%v", err)
+	}
+	if o.Mode == NodeMode && o.Controller.EfsAPIHealthCheck {
+		invalid("controller.efsAPIHealthCheck", "the node does not use the EFS API")
+	}
+	for _, mode := range []struct {
+		field string
+		value string
+	}{
+		{"controller.foreignAccessPointDeletion", o.Controller.ForeignAccessPointDeletion},
+		{"controller.protectedAccessPointDeletion", o.Controller.ProtectedAccessPointDeletion},
+	} {
+		if mode.value != AccessPointDeletionDetach && mode.value != AccessPointDeletionRefuse {
+			invalid(mode.field, "got %q, expected %q or %q", mode.value, AccessPointDeletionDetach, AccessPointDeletionRefuse)
+		}
+	}
+	for _, duration := range []struct {
+		field string
+		value time.Duration
+	}{
+		{"controller.orphanedAccessPointReconcileInterval", o.Controller.OrphanedAccessPointReconcileInterval.Duration},
+		{"controller.orphanedAccessPointGracePeriod", o.Controller.OrphanedAccessPointGracePeriod.Duration},
+		{"controller.orphanedRootDirScanInterval", o.Controller.OrphanedRootDirScanInterval.Duration},
+		{"controller.orphanedRootDirGracePeriod", o.Controller.OrphanedRootDirGracePeriod.Duration},
+		{"node.mountCheckInterval", o.Node.MountCheckInterval.Duration},
+		{"node.mountTimeout", o.Node.MountTimeout.Duration},
+		{"node.unmountTimeout", o.Node.UnmountTimeout.Duration},
+	} {
+		if duration.value < 0 {
+			invalid(duration.field, "must be at least 0, got %v", duration.value)
+		}
+	}
+	if o.Node.VolMetricsRefreshPeriod <= 0 {
+		invalid("node.volMetricsRefreshPeriod", "must be greater than 0, got %v", o.Node.VolMetricsRefreshPeriod)
+	}
+	if o.Node.MountCheckTimeout.Duration <= 0 {
+		invalid("node.mountCheckTimeout", "must be greater than 0, got %v", o.Node.MountCheckTimeout.Duration)
+	}
+	if o.Node.VolMetricsFsRateLimit <= 0 {
+		invalid("node.volMetricsFsRateLimit", "must be greater than 0, got %d", o.Node.VolMetricsFsRateLimit)
+	}
+	if o.Node.VolMetricsWalkConcurrency <= 0 {
+		invalid("node.volMetricsWalkConcurrency", "must be greater than 0, got %d", o.Node.VolMetricsWalkConcurrency)
+	}
+	if o.EfsUtils.ConfigPath == "" {
+		invalid("efsUtils.configPath", "must not be empty")
+	}
+	return errors.Join(errs...)
+}
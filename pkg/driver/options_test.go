@@ -0,0 +1,157 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadDriverOptions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    string
+		expected  func(opts *DriverOptions)
+		expectErr string
+	}{
+		{
+			name:     "only version",
+			config:   "version: v1\n",
+			expected: func(opts *DriverOptions) {},
+		},
+		{
+			name: "yaml sections",
+			config: `version: v1
+mode: node
+controller:
+  clusterId: cluster-1
+  orphanedAccessPointReconcileInterval: 10m
+  orphanedRootDirScanDryRun: false
+node:
+  volMetricsOptIn: true
+metrics:
+  address: ":9910"
+efsUtils:
+  settingsFile: /etc/efs-csi-node/efs-utils-settings.yaml
+`,
+			expected: func(opts *DriverOptions) {
+				opts.Mode = NodeMode
+				opts.Controller.ClusterId = "cluster-1"
+				opts.Controller.OrphanedAccessPointReconcileInterval.Duration = 10 * time.Minute
+				opts.Controller.OrphanedRootDirScanDryRun = false
+				opts.Node.VolMetricsOptIn = true
+				opts.Metrics.Address = ":9910"
+				opts.EfsUtils.SettingsFile = "/etc/efs-csi-node/efs-utils-settings.yaml"
+			},
+		},
+		{
+			name:   "json",
+			config: `{"version": "v1", "node": {"volMetricsFsRateLimit": 10}}`,
+			expected: func(opts *DriverOptions) {
+				opts.Node.VolMetricsFsRateLimit = 10
+			},
+		},
+		{
+			name:      "missing version",
+			config:    "endpoint: unix:///csi/csi.sock\n",
+			expectErr: "unsupported version",
+		},
+		{
+			name:      "unsupported version",
+			config:    "version: v2\n",
+			expectErr: "unsupported version",
+		},
+		{
+			name:      "unknown field",
+			config:    "version: v1\ncontroller:\n  clusterName: cluster-1\n",
+			expectErr: "failed to parse",
+		},
+		{
+			name:      "config path is not configurable",
+			config:    "version: v1\nefsUtils:\n  configPath: /tmp\n",
+			expectErr: "failed to parse",
+		},
+		{
+			name:      "invalid duration",
+			config:    "version: v1\ncontroller:\n  orphanedAccessPointGracePeriod: 1 day\n",
+			expectErr: "failed to parse",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "driver-config.yaml")
+			if err := os.WriteFile(file, []byte(tc.config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			opts, err := LoadDriverOptions(file)
+			if tc.expectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := DefaultDriverOptions()
+			tc.expected(expected)
+			if !reflect.DeepEqual(opts, expected) {
+				t.Fatalf("Expected options %+v, got %+v", expected, opts)
+			}
+		})
+	}
+}
+
+func TestValidateDriverOptions(t *testing.T) {
+	if err := DefaultDriverOptions().Validate(); err != nil {
+		t.Fatalf("Expected default options to be valid, got %v", err)
+	}
+
+	opts := DefaultDriverOptions()
+	opts.Mode = "standalone"
+	opts.Controller.ForeignAccessPointDeletion = "delete"
+	opts.Controller.OrphanedRootDirScanInterval.Duration = -time.Minute
+	opts.Node.VolMetricsFsRateLimit = 0
+	opts.Node.VolMetricsWalkConcurrency = -1
+	opts.Node.MountCheckTimeout.Duration = 0
+	opts.Node.UnmountTimeout.Duration = -time.Second
+	err := opts.Validate()
+	if err == nil {
+		t.Fatalf("Expected invalid options to fail validation")
+	}
+	for _, field := range []string{"mode", "controller.foreignAccessPointDeletion", "controller.orphanedRootDirScanInterval", "node.volMetricsFsRateLimit", "node.volMetricsWalkConcurrency", "node.mountCheckTimeout", "node.unmountTimeout"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("Expected error to report %s, got %v", field, err)
+		}
+	}
+}
+
+func TestValidateDriverOptionsNodeMode(t *testing.T) {
+	opts := DefaultDriverOptions()
+	opts.Mode = NodeMode
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Expected node options to be valid, got %v", err)
+	}
+	opts.Controller.EfsAPIHealthCheck = true
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "controller.efsAPIHealthCheck:") {
+		t.Fatalf("Expected the EFS API health check to be invalid in node mode, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+// orphanedAccessPointReconciler finds the access points tagged by the driver that no PersistentVolume uses,
+// e.g. because the PV was force-deleted or the cluster was torn down without deleting its volumes. It
+// reports them through metrics and Events on the StorageClasses of their file system, and deletes them after
+// a grace period if deleteOrphans is set.
+type orphanedAccessPointReconciler struct {
+	client        kubernetes.Interface
+	cloud         cloud.Cloud
+	recorder      record.EventRecorder
+	interval      time.Duration
+	gracePeriod   time.Duration
+	deleteOrphans bool
+	// clusterId skips the access points owned by other clusters, whose PersistentVolumes are not known here.
+	clusterId string
+	// orphanedSince is when each orphaned access point was first found, by file system ID and access point ID.
+	orphanedSince map[string]map[string]time.Time
+	now           func() time.Time
+}
+
+func (d *Driver) newOrphanedAccessPointReconciler() *orphanedAccessPointReconciler {
+	return &orphanedAccessPointReconciler{
+		client:        d.kubeClient,
+		cloud:         d.cloud,
+		recorder:      d.eventRecorder.recorder,
+		interval:      d.orphanedAccessPointReconcileInterval,
+		gracePeriod:   d.orphanedAccessPointGracePeriod,
+		deleteOrphans: d.deleteOrphanedAccessPoints,
+		clusterId:     d.clusterId,
+		orphanedSince: map[string]map[string]time.Time{},
+		now:           time.Now,
+	}
+}
+
+func (r *orphanedAccessPointReconciler) run(ctx context.Context) {
+	klog.Infof("Starting orphaned access point reconciler with interval %v", r.interval)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reconcile(ctx); err != nil {
+			reconcileErrors.WithLabelValues("orphaned-access-points").Inc()
+			klog.Errorf("Failed to reconcile orphaned access points: %v", err)
+		}
+	}, r.interval)
+}
+
+func (r *orphanedAccessPointReconciler) reconcile(ctx context.Context) error {
+	storageClasses, err := r.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list StorageClasses: %v", err)
+	}
+	pvs, err := r.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list PersistentVolumes: %v", err)
+	}
+
+	// The file systems of the driver's StorageClasses and PVs, with the StorageClasses to record Events on.
+	fileSystems := map[string][]*storagev1.StorageClass{}
+	for i := range storageClasses.Items {
+		sc := &storageClasses.Items[i]
+		if sc.Provisioner == driverName && sc.Parameters[FsId] != "" {
+			fileSystems[sc.Parameters[FsId]] = append(fileSystems[sc.Parameters[FsId]], sc)
+		}
+	}
+	usedAccessPoints := map[string]bool{}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+			continue
+		}
+		fileSystemId, _, accessPointId, err := parseVolumeId(pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			klog.V(4).Infof("Skipping PV %s with invalid volume handle: %v", pv.Name, err)
+			continue
+		}
+		if _, ok := fileSystems[fileSystemId]; !ok {
+			fileSystems[fileSystemId] = nil
+		}
+		if accessPointId != "" {
+			usedAccessPoints[accessPointId] = true
+		}
+	}
+
+	now := r.now()
+	orphanedSince := map[string]map[string]time.Time{}
+	var errs []error
+	for fileSystemId, storageClasses := range fileSystems {
+		accessPoints, err := r.cloud.ListAccessPoints(ctx, fileSystemId)
+		if err != nil {
+			if err == cloud.ErrNotFound {
+				// e.g. a file system of another account, which is only reachable with the role of a StorageClass secret
+				klog.V(4).Infof("Skipping file system %s: %v", fileSystemId, err)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to list access points of file system %s: %v", fileSystemId, err))
+			// Keep the grace period running until the access points can be listed again
+			orphanedSince[fileSystemId] = r.orphanedSince[fileSystemId]
+			continue
+		}
+
+		orphanedSince[fileSystemId] = map[string]time.Time{}
+		var orphans []string
+		for _, ap := range accessPoints {
+			if ap == nil || ap.Tags[DefaultTagKey] != DefaultTagValue || usedAccessPoints[ap.AccessPointId] || isForeignAccessPoint(r.clusterId, ap) {
+				continue
+			}
+			since, ok := r.orphanedSince[fileSystemId][ap.AccessPointId]
+			if !ok {
+				since = now
+			}
+			// Access points protected from deletion are reported but never deleted.
+			if r.deleteOrphans && now.Sub(since) >= r.gracePeriod && ap.Tags[DeletionProtectionTagKey] != "true" {
+				err := r.cloud.DeleteAccessPoint(ctx, ap.AccessPointId)
+				if err == nil || err == cloud.ErrNotFound {
+					klog.Infof("Deleted access point %s of file system %s, orphaned since %v", ap.AccessPointId, fileSystemId, since)
+					orphanedAccessPointsDeleted.WithLabelValues(fileSystemId).Inc()
+					r.event(storageClasses, corev1.EventTypeNormal, ReasonOrphanedAccessPointDeleted,
+						fmt.Sprintf("Deleted access point %s of file system %s, which was not used by any PersistentVolume since %v", ap.AccessPointId, fileSystemId, since.Format(time.RFC3339)))
+					continue
+				}
+				errs = append(errs, fmt.Errorf("failed to delete access point %s: %v", ap.AccessPointId, err))
+			}
+			orphanedSince[fileSystemId][ap.AccessPointId] = since
+			orphans = append(orphans, ap.AccessPointId)
+		}
+
+		orphanedAccessPoints.WithLabelValues(fileSystemId).Set(float64(len(orphans)))
+		if len(orphans) > 0 {
+			message := fmt.Sprintf("%d access points of file system %s are not used by any PersistentVolume: %s", len(orphans), fileSystemId, strings.Join(orphans, ", "))
+			klog.Warning(message)
+			r.event(storageClasses, corev1.EventTypeWarning, ReasonOrphanedAccessPoints, message)
+		}
+	}
+	r.orphanedSince = orphanedSince
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *orphanedAccessPointReconciler) event(storageClasses []*storagev1.StorageClass, eventtype, reason, message string) {
+	for _, sc := range storageClasses {
+		r.recorder.Event(sc, eventtype, reason, message)
+	}
+}
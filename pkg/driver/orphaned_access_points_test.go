@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+)
+
+func TestOrphanedAccessPointReconciler(t *testing.T) {
+	var (
+		fsId         = "fs-abcd1234"
+		usedApId     = "fsap-used"
+		orphanApId   = "fsap-orphan"
+		untaggedApId = "fsap-untagged"
+		foreignApId  = "fsap-foreign"
+		now          = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		gracePeriod  = 24 * time.Hour
+		driverTags   = map[string]string{DefaultTagKey: DefaultTagValue}
+		storageClass = &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "efs-sc"},
+			Provisioner: driverName,
+			Parameters:  map[string]string{ProvisioningMode: AccessPointMode, FsId: fsId},
+		}
+		pv = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: fsId + "::" + usedApId},
+				},
+			},
+		}
+		accessPoints = []*cloud.AccessPoint{
+			{AccessPointId: usedApId, FileSystemId: fsId, Tags: driverTags},
+			{AccessPointId: orphanApId, FileSystemId: fsId, Tags: driverTags},
+			{AccessPointId: untaggedApId, FileSystemId: fsId, Tags: map[string]string{}},
+			// Used by a PersistentVolume of another cluster sharing the file system.
+			{AccessPointId: foreignApId, FileSystemId: fsId, Tags: map[string]string{DefaultTagKey: DefaultTagValue, OwnerClusterTagKey: "cluster-b"}},
+		}
+	)
+
+	testCases := []struct {
+		name                string
+		deleteOrphans       bool
+		orphanedSince       time.Time
+		protected           bool
+		listErr             error
+		expectDelete        bool
+		expectErr           bool
+		expectEvent         string
+		expectOrphans       float64
+		expectOrphanedSince bool
+	}{
+		{
+			name:                "report orphan",
+			expectEvent:         ReasonOrphanedAccessPoints,
+			expectOrphans:       1,
+			expectOrphanedSince: true,
+		},
+		{
+			name:                "keep orphan within grace period",
+			deleteOrphans:       true,
+			orphanedSince:       now.Add(-time.Hour),
+			expectEvent:         ReasonOrphanedAccessPoints,
+			expectOrphans:       1,
+			expectOrphanedSince: true,
+		},
+		{
+			name:          "delete orphan after grace period",
+			deleteOrphans: true,
+			orphanedSince: now.Add(-gracePeriod),
+			expectDelete:  true,
+			expectEvent:   ReasonOrphanedAccessPointDeleted,
+			expectOrphans: 0,
+		},
+		{
+			name:                "keep orphan protected from deletion",
+			deleteOrphans:       true,
+			orphanedSince:       now.Add(-gracePeriod),
+			protected:           true,
+			expectEvent:         ReasonOrphanedAccessPoints,
+			expectOrphans:       1,
+			expectOrphanedSince: true,
+		},
+		{
+			name:                "keep grace period running when access points cannot be listed",
+			deleteOrphans:       true,
+			orphanedSince:       now.Add(-time.Hour),
+			listErr:             errors.New("DescribeAccessPoints failed"),
+			expectErr:           true,
+			expectOrphanedSince: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			recorder := record.NewFakeRecorder(10)
+			orphanedAccessPoints.Reset()
+
+			r := &orphanedAccessPointReconciler{
+				client:        fake.NewSimpleClientset([]runtime.Object{storageClass, pv}...),
+				cloud:         mockCloud,
+				recorder:      recorder,
+				gracePeriod:   gracePeriod,
+				deleteOrphans: tc.deleteOrphans,
+				clusterId:     "cluster-a",
+				orphanedSince: map[string]map[string]time.Time{},
+				now:           func() time.Time { return now },
+			}
+			if !tc.orphanedSince.IsZero() {
+				r.orphanedSince[fsId] = map[string]time.Time{orphanApId: tc.orphanedSince}
+			}
+
+			ctx := context.Background()
+			if tc.listErr != nil {
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(nil, tc.listErr)
+			} else if tc.protected {
+				protectedAccessPoints := append([]*cloud.AccessPoint{}, accessPoints...)
+				protectedAccessPoints[1] = &cloud.AccessPoint{AccessPointId: orphanApId, FileSystemId: fsId,
+					Tags: map[string]string{DefaultTagKey: DefaultTagValue, DeletionProtectionTagKey: "true"}}
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(protectedAccessPoints, nil)
+			} else {
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(accessPoints, nil)
+			}
+			if tc.expectDelete {
+				mockCloud.EXPECT().DeleteAccessPoint(gomock.Eq(ctx), gomock.Eq(orphanApId)).Return(nil)
+			}
+
+			err := r.reconcile(ctx)
+			if tc.expectErr && err == nil {
+				t.Fatalf("Expected reconcile to fail")
+			}
+			if !tc.expectErr && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if tc.expectEvent != "" {
+				select {
+				case event := <-recorder.Events:
+					if !containsReason(event, tc.expectEvent) {
+						t.Fatalf("Expected %s event, got %q", tc.expectEvent, event)
+					}
+				default:
+					t.Fatalf("Expected %s event, got no event", tc.expectEvent)
+				}
+			}
+			if !tc.expectErr {
+				if orphans := testutil.ToFloat64(orphanedAccessPoints.WithLabelValues(fsId)); orphans != tc.expectOrphans {
+					t.Fatalf("Expected %v orphaned access points, got %v", tc.expectOrphans, orphans)
+				}
+			}
+			if _, ok := r.orphanedSince[fsId][orphanApId]; ok != tc.expectOrphanedSince {
+				t.Fatalf("Expected orphaned access point to be tracked: %v, got %v", tc.expectOrphanedSince, ok)
+			}
+		})
+	}
+}
+
+func containsReason(event, reason string) bool {
+	for _, eventtype := range []string{corev1.EventTypeNormal, corev1.EventTypeWarning} {
+		prefix := eventtype + " " + reason + " "
+		if len(event) >= len(prefix) && event[:len(prefix)] == prefix {
+			return true
+		}
+	}
+	return false
+}
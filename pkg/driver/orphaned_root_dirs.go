@@ -0,0 +1,377 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+const (
+	// provisionerSecretNameKey is set on StorageClasses passing a role for cross-account provisioning, whose file
+	// systems are not scanned since their mount target is only known to that role.
+	provisionerSecretNameKey = "csi.storage.k8s.io/provisioner-secret-name"
+	// maxListedOrphanedRootDirs limits how many directories are named in an Event.
+	maxListedOrphanedRootDirs = 10
+)
+
+// orphanedRootDirScanner finds the directories under the basePath of the driver's StorageClasses that are not the
+// root directory of any access point of the file system or the directory of an efs-dir PersistentVolume, or a
+// parent of one. Directories archived by DeleteVolume are kept. They are left behind when access
+// points are deleted outside the driver or their root directory could not be deleted. It reports them and their
+// size through metrics and Events on the StorageClasses, and deletes them unless dryRun is set. A directory is only
+// deleted once it was found orphaned for gracePeriod, since CreateVolume makes the directory of an efs-dir volume
+// before its PersistentVolume exists, and if it is still orphaned right before deletion.
+//
+// StorageClasses without basePath are not scanned, since the root of the file system may hold any other data.
+type orphanedRootDirScanner struct {
+	client   kubernetes.Interface
+	cloud    cloud.Cloud
+	recorder record.EventRecorder
+	interval time.Duration
+	dryRun   bool
+	// gracePeriod is how long a directory must be orphaned before it is deleted.
+	gracePeriod time.Duration
+	// orphanedSince is when each orphaned directory was first found, by file system ID and path.
+	orphanedSince map[string]map[string]time.Time
+	now           func() time.Time
+	// mountPathPrefix is where the file systems are mounted while they are scanned.
+	mountPathPrefix string
+	mount           func(ctx context.Context, fileSystemId, target string) error
+	unmount         func(target string) error
+}
+
+func (d *Driver) newOrphanedRootDirScanner() *orphanedRootDirScanner {
+	return &orphanedRootDirScanner{
+		client:          d.kubeClient,
+		cloud:           d.cloud,
+		recorder:        d.eventRecorder.recorder,
+		interval:        d.orphanedRootDirScanInterval,
+		dryRun:          d.orphanedRootDirScanDryRun,
+		gracePeriod:     d.orphanedRootDirGracePeriod,
+		orphanedSince:   map[string]map[string]time.Time{},
+		now:             time.Now,
+		mountPathPrefix: TempMountPathPrefix,
+		mount: func(ctx context.Context, fileSystemId, target string) error {
+			return d.mountFileSystemRoot(ctx, d.cloud, fileSystemId, target, "", false)
+		},
+		unmount: d.unmountFileSystemRoot,
+	}
+}
+
+func (s *orphanedRootDirScanner) run(ctx context.Context) {
+	klog.Infof("Starting orphaned root directory scanner with interval %v, dry run %v", s.interval, s.dryRun)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.scan(ctx); err != nil {
+			reconcileErrors.WithLabelValues("orphaned-root-directories").Inc()
+			klog.Errorf("Failed to scan for orphaned root directories: %v", err)
+		}
+	}, s.interval)
+}
+
+func (s *orphanedRootDirScanner) scan(ctx context.Context) error {
+	storageClasses, err := s.client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list StorageClasses: %v", err)
+	}
+
+	// The basePaths of each file system, with the StorageClasses to record Events on.
+	basePaths := map[string]map[string][]*storagev1.StorageClass{}
+	for i := range storageClasses.Items {
+		sc := &storageClasses.Items[i]
+		fileSystemId := sc.Parameters[FsId]
+		basePath := path.Clean("/" + sc.Parameters[BasePath])
+		if sc.Provisioner != driverName || fileSystemId == "" {
+			continue
+		}
+		if basePath == "/" || sc.Parameters[provisionerSecretNameKey] != "" {
+			klog.V(4).Infof("Not scanning StorageClass %s without basePath or with a cross-account role", sc.Name)
+			continue
+		}
+		if basePaths[fileSystemId] == nil {
+			basePaths[fileSystemId] = map[string][]*storagev1.StorageClass{}
+		}
+		basePaths[fileSystemId][basePath] = append(basePaths[fileSystemId][basePath], sc)
+	}
+
+	now := s.now()
+	orphanedSince := map[string]map[string]time.Time{}
+	var errs []error
+	for fileSystemId, fsBasePaths := range basePaths {
+		// Keep the grace period running if the file system could not be scanned.
+		orphanedSince[fileSystemId] = s.orphanedSince[fileSystemId]
+		since, err := s.scanFileSystem(ctx, fileSystemId, fsBasePaths, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file system %s: %v", fileSystemId, err))
+		}
+		if since != nil {
+			orphanedSince[fileSystemId] = since
+		}
+	}
+	s.orphanedSince = orphanedSince
+	return utilerrors.NewAggregate(errs)
+}
+
+// referencedDirs returns the directories of fileSystemId in use by its access points and efs-dir volumes. It
+// returns cloud.ErrNotFound if the access points of the file system can not be listed with the driver's role.
+func (s *orphanedRootDirScanner) referencedDirs(ctx context.Context, fileSystemId string) (*referencedDirs, error) {
+	pvs, err := s.client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PersistentVolumes: %v", err)
+	}
+	accessPoints, err := s.cloud.ListAccessPoints(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to list access points: %v", err)
+	}
+
+	var rootDirs []string
+	// The directories of efs-dir and static volumes, which are not access points.
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+			continue
+		}
+		pvFileSystemId, subpath, accessPointId, err := parseVolumeId(pv.Spec.CSI.VolumeHandle)
+		if err != nil || pvFileSystemId != fileSystemId || accessPointId != "" || subpath == "" {
+			continue
+		}
+		rootDirs = append(rootDirs, subpath)
+	}
+	for _, ap := range accessPoints {
+		if ap != nil && ap.AccessPointRootDir != "" {
+			rootDirs = append(rootDirs, ap.AccessPointRootDir)
+		}
+	}
+	return newReferencedDirs(rootDirs), nil
+}
+
+// scanFileSystem reports and deletes the orphaned directories of fileSystemId, and returns when each directory that
+// is still orphaned was first found, or nil if the file system was not scanned.
+func (s *orphanedRootDirScanner) scanFileSystem(ctx context.Context, fileSystemId string, basePaths map[string][]*storagev1.StorageClass, now time.Time) (map[string]time.Time, error) {
+	referenced, err := s.referencedDirs(ctx, fileSystemId)
+	if err != nil {
+		if err == cloud.ErrNotFound {
+			klog.V(4).Infof("Skipping file system %s: %v", fileSystemId, err)
+			return map[string]time.Time{}, nil
+		}
+		return nil, err
+	}
+
+	target := filepath.Join(s.mountPathPrefix, "scan-"+fileSystemId)
+	if err := s.mount(ctx, fileSystemId, target); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := s.unmount(target); err != nil {
+			klog.Errorf("Failed to unmount %s after scanning it: %v", target, err)
+		}
+	}()
+
+	var (
+		errs          []error
+		orphanedDirs  int
+		orphanedBytes int64
+		orphanedSince = map[string]time.Time{}
+		// recheck holds the directories in use right before the first deletion, to not delete the directory of a
+		// volume created during the scan.
+		recheck    *referencedDirs
+		recheckErr error
+	)
+	for basePath, storageClasses := range basePaths {
+		orphans, err := findOrphanedRootDirs(target, basePath, referenced)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var bytes, deletedBytes int64
+		var kept, deleted []string
+		for _, dir := range orphans {
+			since, ok := s.orphanedSince[fileSystemId][dir]
+			if !ok {
+				since = now
+			}
+			expired := !s.dryRun && now.Sub(since) >= s.gracePeriod
+			if expired && recheck == nil && recheckErr == nil {
+				if recheck, recheckErr = s.referencedDirs(ctx, fileSystemId); recheckErr != nil {
+					errs = append(errs, fmt.Errorf("failed to check the directories in use before deleting: %v", recheckErr))
+				}
+			}
+			if recheck != nil && recheck.inUse(dir) {
+				klog.V(4).Infof("Directory %s of file system %s is no longer orphaned", dir, fileSystemId)
+				continue
+			}
+			dirBytes, err := dirSize(filepath.Join(target, dir))
+			if err != nil {
+				klog.Warningf("Could not compute size of %s on file system %s: %v", dir, fileSystemId, err)
+			}
+			bytes += dirBytes
+			if !expired || recheck == nil {
+				orphanedSince[dir] = since
+				kept = append(kept, dir)
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(target, dir)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete %s: %v", dir, err))
+				orphanedSince[dir] = since
+				kept = append(kept, dir)
+				continue
+			}
+			klog.Infof("Deleted orphaned root directory %s of file system %s, orphaned since %v", dir, fileSystemId, since)
+			orphanedRootDirsDeleted.WithLabelValues(fileSystemId).Inc()
+			deletedBytes += dirBytes
+			deleted = append(deleted, dir)
+		}
+		orphanedDirs += len(kept) + len(deleted)
+		orphanedBytes += bytes
+
+		if len(kept) > 0 {
+			size := resource.NewQuantity(bytes-deletedBytes, resource.BinarySI).String()
+			message := fmt.Sprintf("%d directories (%s) under basePath %s of file system %s are not used by any access point: %s",
+				len(kept), size, basePath, fileSystemId, listDirs(kept))
+			klog.Warning(message)
+			s.event(storageClasses, corev1.EventTypeWarning, ReasonOrphanedRootDirectories, message)
+		}
+		if len(deleted) > 0 {
+			size := resource.NewQuantity(deletedBytes, resource.BinarySI).String()
+			message := fmt.Sprintf("Deleted %d directories (%s) under basePath %s of file system %s, which were not used by any access point: %s",
+				len(deleted), size, basePath, fileSystemId, listDirs(deleted))
+			s.event(storageClasses, corev1.EventTypeNormal, ReasonOrphanedRootDirectoriesDeleted, message)
+		}
+	}
+	orphanedRootDirs.WithLabelValues(fileSystemId).Set(float64(orphanedDirs))
+	orphanedRootDirBytes.WithLabelValues(fileSystemId).Set(float64(orphanedBytes))
+	return orphanedSince, utilerrors.NewAggregate(errs)
+}
+
+func (s *orphanedRootDirScanner) event(storageClasses []*storagev1.StorageClass, eventtype, reason, message string) {
+	for _, sc := range storageClasses {
+		s.recorder.Event(sc, eventtype, reason, message)
+	}
+}
+
+// referencedDirs are the directories of a file system that are in use: the root directories of its access points
+// and the directories of efs-dir volumes, and their parents.
+type referencedDirs struct {
+	rootDirs map[string]bool
+	parents  map[string]bool
+}
+
+func newReferencedDirs(rootDirs []string) *referencedDirs {
+	r := &referencedDirs{rootDirs: map[string]bool{}, parents: map[string]bool{}}
+	for _, dir := range rootDirs {
+		dir = path.Clean("/" + dir)
+		r.rootDirs[dir] = true
+		for parent := path.Dir(dir); parent != "/"; parent = path.Dir(parent) {
+			r.parents[parent] = true
+		}
+	}
+	return r
+}
+
+// inUse returns whether dir is in use, under a directory in use, or the parent of one.
+func (r *referencedDirs) inUse(dir string) bool {
+	dir = path.Clean("/" + dir)
+	if r.parents[dir] {
+		return true
+	}
+	for ; dir != "/"; dir = path.Dir(dir) {
+		if r.rootDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// findOrphanedRootDirs walks basePath of the file system mounted at root, and returns the paths relative to root of
+// the directories that are neither in use nor under a directory in use.
+func findOrphanedRootDirs(root, basePath string, referenced *referencedDirs) ([]string, error) {
+	var orphans []string
+	err := filepath.WalkDir(filepath.Join(root, basePath), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == filepath.Join(root, basePath) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		dir := "/" + strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+		switch {
+		case referenced.rootDirs[dir] || strings.HasPrefix(d.Name(), archivedDirPrefix):
+			return filepath.SkipDir
+		case dir == basePath || referenced.parents[dir]:
+			return nil
+		default:
+			orphans = append(orphans, dir)
+			return filepath.SkipDir
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %v", basePath, err)
+	}
+	sort.Strings(orphans)
+	return orphans, nil
+}
+
+// dirSize returns the total size of the files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func listDirs(dirs []string) string {
+	if len(dirs) > maxListedOrphanedRootDirs {
+		return fmt.Sprintf("%s and %d more", strings.Join(dirs[:maxListedOrphanedRootDirs], ", "), len(dirs)-maxListedOrphanedRootDirs)
+	}
+	return strings.Join(dirs, ", ")
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+)
+
+func TestFindOrphanedRootDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"/dynamic/pvc-used/data",
+		"/dynamic/pvc-orphan/data",
+		"/dynamic/team/pvc-nested",
+		"/dynamic/team/pvc-nested-orphan",
+		"/dynamic/.archived-pvc-deleted-1700000000",
+		"/other/pvc-outside-base-path",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "/dynamic/file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	referenced := newReferencedDirs([]string{"/dynamic/pvc-used", "dynamic/team/pvc-nested", "/other/pvc-outside-base-path"})
+
+	testCases := []struct {
+		name     string
+		basePath string
+		expected []string
+	}{
+		{
+			name:     "orphans under base path",
+			basePath: "/dynamic",
+			expected: []string{"/dynamic/pvc-orphan", "/dynamic/team/pvc-nested-orphan"},
+		},
+		{
+			name:     "base path is an access point root",
+			basePath: "/dynamic/pvc-used",
+		},
+		{
+			name:     "missing base path",
+			basePath: "/missing",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orphans, err := findOrphanedRootDirs(root, tc.basePath, referenced)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(orphans, tc.expected) {
+				t.Fatalf("Expected orphans %v, got %v", tc.expected, orphans)
+			}
+		})
+	}
+}
+
+func TestOrphanedRootDirScanner(t *testing.T) {
+	var (
+		fsId         = "fs-abcd1234"
+		storageClass = &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "efs-sc"},
+			Provisioner: driverName,
+			Parameters:  map[string]string{ProvisioningMode: AccessPointMode, FsId: fsId, BasePath: "/dynamic"},
+		}
+		// A volume provisioned in efs-dir mode.
+		pv = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: provisionedDirectoryVolumeId(fsId, "/dynamic/pvc-dir")},
+				},
+			},
+		}
+		accessPoints = []*cloud.AccessPoint{
+			{AccessPointId: "fsap-used", FileSystemId: fsId, AccessPointRootDir: "/dynamic/pvc-used"},
+		}
+		now         = time.Now()
+		gracePeriod = 24 * time.Hour
+	)
+
+	testCases := []struct {
+		name   string
+		dryRun bool
+		// orphanedFor is how long the orphan was found orphaned by previous scans, 0 if never.
+		orphanedFor time.Duration
+		// recheckAccessPoints, if set, are the access points listed right before deleting.
+		recheckAccessPoints []*cloud.AccessPoint
+		expectEvent         string
+		expectExists        bool
+		expectOrphans       float64
+	}{
+		{
+			name:          "dry run reports orphans",
+			dryRun:        true,
+			orphanedFor:   2 * gracePeriod,
+			expectEvent:   ReasonOrphanedRootDirectories,
+			expectExists:  true,
+			expectOrphans: 1,
+		},
+		{
+			name:                "delete orphans after grace period",
+			orphanedFor:         gracePeriod,
+			recheckAccessPoints: accessPoints,
+			expectEvent:         ReasonOrphanedRootDirectoriesDeleted,
+			expectOrphans:       1,
+		},
+		{
+			name:          "keep new orphans",
+			expectEvent:   ReasonOrphanedRootDirectories,
+			expectExists:  true,
+			expectOrphans: 1,
+		},
+		{
+			name:          "keep orphans within grace period",
+			orphanedFor:   gracePeriod - time.Minute,
+			expectEvent:   ReasonOrphanedRootDirectories,
+			expectExists:  true,
+			expectOrphans: 1,
+		},
+		{
+			name:        "keep orphans used by an access point created during the scan",
+			orphanedFor: gracePeriod,
+			recheckAccessPoints: append([]*cloud.AccessPoint{
+				{AccessPointId: "fsap-new", FileSystemId: fsId, AccessPointRootDir: "/dynamic/pvc-orphan"},
+			}, accessPoints...),
+			expectExists: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			recorder := record.NewFakeRecorder(10)
+			orphanedRootDirs.Reset()
+			orphanedRootDirBytes.Reset()
+
+			// The file system is "mounted" at the scan target by creating its tree there.
+			mountPathPrefix := t.TempDir()
+			target := filepath.Join(mountPathPrefix, "scan-"+fsId)
+			for _, dir := range []string{"/dynamic/pvc-used", "/dynamic/pvc-dir", "/dynamic/pvc-orphan"} {
+				if err := os.MkdirAll(filepath.Join(target, dir), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(filepath.Join(target, "/dynamic/pvc-orphan/file"), make([]byte, 1024), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			orphanedSince := map[string]map[string]time.Time{}
+			if tc.orphanedFor > 0 {
+				orphanedSince[fsId] = map[string]time.Time{"/dynamic/pvc-orphan": now.Add(-tc.orphanedFor)}
+			}
+			var mounted, unmounted bool
+			s := &orphanedRootDirScanner{
+				client:          fake.NewSimpleClientset(storageClass, pv),
+				cloud:           mockCloud,
+				recorder:        recorder,
+				dryRun:          tc.dryRun,
+				gracePeriod:     gracePeriod,
+				orphanedSince:   orphanedSince,
+				now:             func() time.Time { return now },
+				mountPathPrefix: mountPathPrefix,
+				mount: func(ctx context.Context, fileSystemId, mountTarget string) error {
+					mounted = fileSystemId == fsId && mountTarget == target
+					return nil
+				},
+				unmount: func(mountTarget string) error {
+					unmounted = mountTarget == target
+					return nil
+				},
+			}
+
+			ctx := context.Background()
+			mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(accessPoints, nil)
+			if tc.recheckAccessPoints != nil {
+				mockCloud.EXPECT().ListAccessPoints(gomock.Eq(ctx), gomock.Eq(fsId)).Return(tc.recheckAccessPoints, nil)
+			}
+
+			if err := s.scan(ctx); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !mounted || !unmounted {
+				t.Fatalf("Expected file system to be mounted at and unmounted from %s", target)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !containsReason(event, tc.expectEvent) {
+					t.Fatalf("Expected %s event, got %q", tc.expectEvent, event)
+				}
+			default:
+				if tc.expectEvent != "" {
+					t.Fatalf("Expected %s event, got no event", tc.expectEvent)
+				}
+			}
+			if orphans := testutil.ToFloat64(orphanedRootDirs.WithLabelValues(fsId)); orphans != tc.expectOrphans {
+				t.Fatalf("Expected %v orphaned root directories, got %v", tc.expectOrphans, orphans)
+			}
+			if bytes := testutil.ToFloat64(orphanedRootDirBytes.WithLabelValues(fsId)); bytes != 1024*tc.expectOrphans {
+				t.Fatalf("Expected %v orphaned bytes, got %v", 1024*tc.expectOrphans, bytes)
+			}
+			// The grace period of a kept orphan keeps running from when it was first found.
+			_, tracked := s.orphanedSince[fsId]["/dynamic/pvc-orphan"]
+			if expectTracked := tc.expectExists && tc.expectOrphans > 0; tracked != expectTracked {
+				t.Fatalf("Expected orphan to be tracked: %v, got %v", expectTracked, tracked)
+			}
+			if tc.orphanedFor > 0 && tracked && !s.orphanedSince[fsId]["/dynamic/pvc-orphan"].Equal(now.Add(-tc.orphanedFor)) {
+				t.Fatalf("Expected orphan to be tracked since %v, got %v", now.Add(-tc.orphanedFor), s.orphanedSince[fsId]["/dynamic/pvc-orphan"])
+			}
+
+			_, err := os.Stat(filepath.Join(target, "/dynamic/pvc-orphan"))
+			if exists := err == nil; exists != tc.expectExists {
+				t.Fatalf("Expected orphaned root directory to exist: %v, got %v", tc.expectExists, exists)
+			}
+			for _, dir := range []string{"/dynamic/pvc-used", "/dynamic/pvc-dir"} {
+				if _, err := os.Stat(filepath.Join(target, dir)); err != nil {
+					t.Fatalf("Expected used directory %s to be kept: %v", dir, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+const (
+	// OwnerClusterTagKey is set to the cluster ID of the controller on the access points it creates.
+	OwnerClusterTagKey = "efs.csi.aws.com/owner-cluster"
+	// TakeOwnershipAnnotation on a PersistentVolume makes the controller tag its access point as owned by its
+	// cluster, for example after migrating volumes to a new cluster.
+	TakeOwnershipAnnotation = "efs.csi.aws.com/take-ownership"
+
+	ownershipReconcileInterval = time.Minute
+)
+
+// accessPointOwner returns the cluster owning an access point, if it is tagged with one.
+func accessPointOwner(accessPoint *cloud.AccessPoint) string {
+	if accessPoint == nil {
+		return ""
+	}
+	return accessPoint.Tags[OwnerClusterTagKey]
+}
+
+// isForeignAccessPoint tells whether an access point is owned by another cluster than clusterId. Access points
+// without an owner, created before cluster IDs were set, belong to every cluster.
+func isForeignAccessPoint(clusterId string, accessPoint *cloud.AccessPoint) bool {
+	owner := accessPointOwner(accessPoint)
+	return clusterId != "" && owner != "" && owner != clusterId
+}
+
+// ownershipReconciler tags the access points of the PersistentVolumes annotated with TakeOwnershipAnnotation as
+// owned by clusterId, and removes the annotation.
+type ownershipReconciler struct {
+	client kubernetes.Interface
+	pvs    *pvLookup
+	// cloudFor returns the cloud managing a file system used in namespace, honoring the role mapping.
+	cloudFor  func(namespace, fileSystemId string) (cloud.Cloud, error)
+	recorder  record.EventRecorder
+	clusterId string
+	interval  time.Duration
+}
+
+func (d *Driver) newOwnershipReconciler() *ownershipReconciler {
+	return &ownershipReconciler{
+		client: d.kubeClient,
+		pvs:    d.pvs,
+		cloudFor: func(namespace, fileSystemId string) (cloud.Cloud, error) {
+			localCloud, _, _, err := getCloud(nil, d, namespace, fileSystemId)
+			return localCloud, err
+		},
+		recorder:  d.eventRecorder.recorder,
+		clusterId: d.clusterId,
+		interval:  ownershipReconcileInterval,
+	}
+}
+
+func (r *ownershipReconciler) run(ctx context.Context) {
+	klog.Infof("Starting access point ownership reconciler for cluster %s", r.clusterId)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reconcile(ctx); err != nil {
+			reconcileErrors.WithLabelValues("access-point-ownership").Inc()
+			klog.Errorf("Failed to transfer ownership of access points: %v", err)
+		}
+	}, r.interval)
+}
+
+func (r *ownershipReconciler) reconcile(ctx context.Context) error {
+	pvs, err := r.pvs.list(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list PersistentVolumes: %v", err)
+	}
+
+	var errs []error
+	for _, pv := range pvs {
+		if pv.Annotations[TakeOwnershipAnnotation] != "true" {
+			continue
+		}
+		if err := r.takeOwnership(ctx, pv); err != nil {
+			errs = append(errs, fmt.Errorf("PV %s: %v", pv.Name, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *ownershipReconciler) takeOwnership(ctx context.Context, pv *corev1.PersistentVolume) error {
+	fileSystemId, _, accessPointId, err := parseVolumeId(pv.Spec.CSI.VolumeHandle)
+	if err != nil {
+		return err
+	}
+	if accessPointId != "" {
+		var namespace string
+		if pv.Spec.ClaimRef != nil {
+			namespace = pv.Spec.ClaimRef.Namespace
+		}
+		localCloud, err := r.cloudFor(namespace, fileSystemId)
+		if err != nil {
+			return err
+		}
+		err = localCloud.TagAccessPoint(ctx, accessPointId, map[string]string{OwnerClusterTagKey: r.clusterId})
+		if err != nil {
+			return fmt.Errorf("failed to tag access point %s: %v", accessPointId, err)
+		}
+		message := fmt.Sprintf("Access point %s is now owned by cluster %s", accessPointId, r.clusterId)
+		klog.Info(message)
+		r.recorder.Event(pv, corev1.EventTypeNormal, ReasonAccessPointOwnershipTransferred, message)
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, TakeOwnershipAnnotation))
+	_, err = r.client.CoreV1().PersistentVolumes().Patch(ctx, pv.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to remove annotation %s: %v", TakeOwnershipAnnotation, err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+)
+
+func TestIsForeignAccessPoint(t *testing.T) {
+	testCases := []struct {
+		name      string
+		clusterId string
+		owner     string
+		expected  bool
+	}{
+		{name: "owned by this cluster", clusterId: "cluster-a", owner: "cluster-a"},
+		{name: "owned by another cluster", clusterId: "cluster-a", owner: "cluster-b", expected: true},
+		{name: "no owner", clusterId: "cluster-a"},
+		{name: "no cluster ID", owner: "cluster-b"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			accessPoint := &cloud.AccessPoint{Tags: map[string]string{}}
+			if tc.owner != "" {
+				accessPoint.Tags[OwnerClusterTagKey] = tc.owner
+			}
+			if foreign := isForeignAccessPoint(tc.clusterId, accessPoint); foreign != tc.expected {
+				t.Fatalf("Expected foreign %v, got %v", tc.expected, foreign)
+			}
+		})
+	}
+}
+
+func TestOwnershipReconciler(t *testing.T) {
+	var (
+		apId = "fsap-abcd1234xyz987"
+		pv   = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pv",
+				Annotations: map[string]string{TakeOwnershipAnnotation: "true"},
+			},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "fs-abcd1234::" + apId},
+				},
+				ClaimRef: &corev1.ObjectReference{Namespace: "ns", Name: "pvc"},
+			},
+		}
+		// Not annotated, so its access point is left alone.
+		otherPv = &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-pv"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "fs-abcd1234::fsap-other"},
+				},
+			},
+		}
+	)
+
+	testCases := []struct {
+		name             string
+		tagErr           error
+		expectErr        bool
+		expectAnnotation bool
+	}{
+		{
+			name: "take ownership",
+		},
+		{
+			name:             "keep annotation when tagging fails",
+			tagErr:           errors.New("TagResource failed"),
+			expectErr:        true,
+			expectAnnotation: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockCtl := gomock.NewController(t)
+			defer mockCtl.Finish()
+			mockCloud := mocks.NewMockCloud(mockCtl)
+			recorder := record.NewFakeRecorder(10)
+			client := fake.NewSimpleClientset(pv.DeepCopy(), otherPv)
+
+			r := &ownershipReconciler{
+				client: client,
+				pvs:    newPVLookup(client),
+				cloudFor: func(namespace, fileSystemId string) (cloud.Cloud, error) {
+					if namespace != "ns" || fileSystemId != "fs-abcd1234" {
+						t.Fatalf("Unexpected cloud for namespace %q and file system %s", namespace, fileSystemId)
+					}
+					return mockCloud, nil
+				},
+				recorder:  recorder,
+				clusterId: "cluster-a",
+			}
+
+			ctx := context.Background()
+			mockCloud.EXPECT().TagAccessPoint(gomock.Eq(ctx), gomock.Eq(apId),
+				gomock.Eq(map[string]string{OwnerClusterTagKey: "cluster-a"})).Return(tc.tagErr)
+
+			err := r.reconcile(ctx)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+
+			updated, err := client.CoreV1().PersistentVolumes().Get(ctx, pv.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := updated.Annotations[TakeOwnershipAnnotation]; ok != tc.expectAnnotation {
+				t.Fatalf("Expected annotation %v, got %v", tc.expectAnnotation, updated.Annotations)
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if tc.expectErr || !containsReason(event, ReasonAccessPointOwnershipTransferred) {
+					t.Fatalf("Unexpected event %q", event)
+				}
+			default:
+				if !tc.expectErr {
+					t.Fatalf("Expected %s event, got no event", ReasonAccessPointOwnershipTransferred)
+				}
+			}
+		})
+	}
+}
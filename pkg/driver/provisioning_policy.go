@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+// provisioningPolicy restricts the file systems, base paths and POSIX identities the PVCs of each namespace may
+// provision volumes with. A volume is allowed if any rule matching the namespace of its PVC allows it.
+type provisioningPolicy struct {
+	Rules []provisioningPolicyRule `json:"rules"`
+}
+
+// provisioningPolicyRule allows the namespaces it matches to provision volumes on its file systems, under its base
+// paths and with its uid and gid ranges. Empty fields allow anything.
+type provisioningPolicyRule struct {
+	// Namespaces and NamespaceSelector select the namespaces the rule applies to. A rule without either applies to
+	// every namespace.
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	FileSystemIds     []string              `json:"fileSystemIds,omitempty"`
+	// BasePaths allows the basePath parameters equal to or under one of them.
+	BasePaths []string `json:"basePaths,omitempty"`
+	UidRange  *idRange `json:"uidRange,omitempty"`
+	GidRange  *idRange `json:"gidRange,omitempty"`
+
+	selector labels.Selector
+}
+
+// idRange is an inclusive range of uids or gids.
+type idRange struct {
+	Min int64 `json:"min"`
+	Max int64 `json:"max"`
+}
+
+// contains tells whether id is in the range. An unset id, -1, is only in a nil range, which allows anything.
+func (r *idRange) contains(id int64) bool {
+	return r == nil || (id >= r.Min && id <= r.Max)
+}
+
+// provisioningRequest is what CreateVolume checks against the policy.
+type provisioningRequest struct {
+	fileSystemId string
+	basePath     string
+	// rootDir is the directory of the volume, or "" if it is not known yet and basePath is checked instead. It is
+	// under basePath unless the names interpolated into it leave it.
+	rootDir string
+	// identity is the uid and gid of the volume, or nil if they are not known yet and not checked. The ids of a
+	// directory may be unset, -1.
+	identity *cloud.PosixUser
+}
+
+// loadProvisioningPolicy reads the policy from file. There is no policy if file is empty.
+func loadProvisioningPolicy(file string) (*provisioningPolicy, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provisioning policy: %v", err)
+	}
+	policy := &provisioningPolicy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse provisioning policy %s: %v", file, err)
+	}
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+		if rule.NamespaceSelector != nil {
+			rule.selector, err = metav1.LabelSelectorAsSelector(rule.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespaceSelector of provisioning policy rule %d: %v", i, err)
+			}
+		}
+		for name, ids := range map[string]*idRange{"uidRange": rule.UidRange, "gidRange": rule.GidRange} {
+			if ids != nil && (ids.Min < 0 || ids.Max < ids.Min) {
+				return nil, fmt.Errorf("invalid %s of provisioning policy rule %d: min must be at least 0 and at most max", name, i)
+			}
+		}
+	}
+	klog.Infof("Loaded provisioning policy with %d rules from %s", len(policy.Rules), file)
+	return policy, nil
+}
+
+// usesNamespaceLabels tells whether the labels of namespaces are needed to match the rules.
+func (p *provisioningPolicy) usesNamespaceLabels() bool {
+	for _, rule := range p.Rules {
+		if rule.selector != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *provisioningPolicyRule) matchesNamespace(namespace string, namespaceLabels labels.Set) bool {
+	if len(r.Namespaces) == 0 && r.selector == nil {
+		return true
+	}
+	for _, name := range r.Namespaces {
+		if name == namespace {
+			return true
+		}
+	}
+	return r.selector != nil && r.selector.Matches(namespaceLabels)
+}
+
+// violation returns why the rule does not allow req, or "" if it does.
+func (r *provisioningPolicyRule) violation(req provisioningRequest) string {
+	if len(r.FileSystemIds) > 0 && !containsString(r.FileSystemIds, req.fileSystemId) {
+		return fmt.Sprintf("file system %s is not in %v", req.fileSystemId, r.FileSystemIds)
+	}
+	if len(r.BasePaths) > 0 {
+		name, dir := "basePath", req.basePath
+		if req.rootDir != "" {
+			name, dir = "root directory", req.rootDir
+		}
+		dir = path.Clean("/" + dir)
+		allowed := false
+		for _, allowedPath := range r.BasePaths {
+			allowedPath = path.Clean("/" + allowedPath)
+			if dir == allowedPath || strings.HasPrefix(dir, strings.TrimSuffix(allowedPath, "/")+"/") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("%s %s is not under %v", name, dir, r.BasePaths)
+		}
+	}
+	if req.identity == nil {
+		return ""
+	}
+	if !r.UidRange.contains(req.identity.Uid) {
+		return fmt.Sprintf("uid %d is not in range %d-%d", req.identity.Uid, r.UidRange.Min, r.UidRange.Max)
+	}
+	if !r.GidRange.contains(req.identity.Gid) {
+		return fmt.Sprintf("gid %d is not in range %d-%d", req.identity.Gid, r.GidRange.Min, r.GidRange.Max)
+	}
+	return ""
+}
+
+// checkProvisioningPolicy fails with PermissionDenied unless the policy allows the PVC namespace of volumeParams
+// to provision req.
+func (d *Driver) checkProvisioningPolicy(ctx context.Context, volumeParams map[string]string, req provisioningRequest) error {
+	if d.provisioningPolicy == nil {
+		return nil
+	}
+	namespace := volumeParams[PvcNamespace]
+	if namespace == "" {
+		return status.Errorf(codes.PermissionDenied, "The provisioning policy requires the PVC namespace in %v, run the external-provisioner with --extra-create-metadata", PvcNamespace)
+	}
+
+	var namespaceLabels labels.Set
+	if d.provisioningPolicy.usesNamespaceLabels() {
+		if d.kubeClient == nil {
+			return status.Errorf(codes.FailedPrecondition, "The namespaceSelector of the provisioning policy requires access to the Kubernetes API")
+		}
+		ns, err := d.kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return status.Errorf(codes.Internal, "Could not get namespace %s for the provisioning policy: %v", namespace, err)
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	var violations []string
+	for i := range d.provisioningPolicy.Rules {
+		rule := &d.provisioningPolicy.Rules[i]
+		if !rule.matchesNamespace(namespace, namespaceLabels) {
+			continue
+		}
+		violation := rule.violation(req)
+		if violation == "" {
+			return nil
+		}
+		violations = append(violations, fmt.Sprintf("rule %d: %s", i, violation))
+	}
+
+	err := status.Errorf(codes.PermissionDenied, "The provisioning policy does not allow namespace %s to provision this volume", namespace)
+	if len(violations) > 0 {
+		err = status.Errorf(codes.PermissionDenied, "The provisioning policy does not allow namespace %s to provision this volume: %s", namespace, strings.Join(violations, "; "))
+	}
+	return d.provisioningFailed(ctx, volumeParams, ReasonProvisioningPolicyViolation, err)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+const testProvisioningPolicy = `
+rules:
+  - namespaces: [team-a]
+    fileSystemIds: [fs-a]
+    basePaths: [/team-a]
+    uidRange: {min: 1000, max: 1999}
+    gidRange: {min: 1000, max: 1999}
+  - namespaceSelector:
+      matchLabels:
+        tenant: b
+    fileSystemIds: [fs-b]
+`
+
+func writeProvisioningPolicy(t *testing.T, policy string) string {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestLoadProvisioningPolicy(t *testing.T) {
+	testCases := []struct {
+		name      string
+		policy    string
+		expectErr bool
+	}{
+		{
+			name:   "valid policy",
+			policy: testProvisioningPolicy,
+		},
+		{
+			name:      "unknown field",
+			policy:    "rules:\n  - fileSystemId: fs-a\n",
+			expectErr: true,
+		},
+		{
+			name:      "invalid gid range",
+			policy:    "rules:\n  - gidRange: {min: 2000, max: 1000}\n",
+			expectErr: true,
+		},
+		{
+			name:      "invalid namespace selector",
+			policy:    "rules:\n  - namespaceSelector:\n      matchExpressions:\n        - {key: tenant, operator: Equals}\n",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := loadProvisioningPolicy(writeProvisioningPolicy(t, tc.policy))
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+
+	if policy, err := loadProvisioningPolicy(""); policy != nil || err != nil {
+		t.Fatalf("Expected no policy without file, got %v, %v", policy, err)
+	}
+}
+
+func TestCheckProvisioningPolicy(t *testing.T) {
+	policy, err := loadProvisioningPolicy(writeProvisioningPolicy(t, testProvisioningPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver := &Driver{
+		provisioningPolicy: policy,
+		kubeClient: fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"tenant": "b"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c"}},
+		),
+	}
+
+	testCases := []struct {
+		name      string
+		namespace string
+		req       provisioningRequest
+		expectErr codes.Code
+	}{
+		{
+			name:      "allowed by namespace name",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a/dynamic", identity: &cloud.PosixUser{Uid: 1000, Gid: 1999}},
+		},
+		{
+			name:      "identity not known yet",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "team-a"},
+		},
+		{
+			name:      "unset uid with restricted uid range",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a", identity: &cloud.PosixUser{Uid: -1, Gid: 1000}},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "unset ids without id ranges",
+			namespace: "team-b",
+			req:       provisioningRequest{fileSystemId: "fs-b", identity: &cloud.PosixUser{Uid: -1, Gid: -1}},
+		},
+		{
+			name:      "allowed by namespace selector",
+			namespace: "team-b",
+			req:       provisioningRequest{fileSystemId: "fs-b", identity: &cloud.PosixUser{Uid: 50000, Gid: 50000}},
+		},
+		{
+			name:      "file system not allowed",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-b", basePath: "/team-a"},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "base path outside allowed paths",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a-other"},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "root directory under allowed paths",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a", rootDir: "/team-a/pvc-1"},
+		},
+		{
+			name:      "root directory leaving allowed paths",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a", rootDir: "/team-a/../team-b/pvc-1"},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "gid outside allowed range",
+			namespace: "team-a",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a", identity: &cloud.PosixUser{Uid: 1000, Gid: 50000}},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "namespace without rule",
+			namespace: "team-c",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a"},
+			expectErr: codes.PermissionDenied,
+		},
+		{
+			name:      "unknown namespace",
+			req:       provisioningRequest{fileSystemId: "fs-a", basePath: "/team-a"},
+			expectErr: codes.PermissionDenied,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			volumeParams := map[string]string{}
+			if tc.namespace != "" {
+				volumeParams[PvcNamespace] = tc.namespace
+			}
+			err := driver.checkProvisioningPolicy(context.Background(), volumeParams, tc.req)
+			if code := status.Code(err); code != tc.expectErr {
+				t.Fatalf("Expected %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
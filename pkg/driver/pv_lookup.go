@@ -0,0 +1,101 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// volumeHandleIndex indexes the PersistentVolumes of the driver by volume handle.
+const volumeHandleIndex = "volumeHandle"
+
+// pvLookup finds the PersistentVolume of a volume, of which DeleteVolume is only given the ID. Rather than listing
+// every PV of the cluster on each call, it watches them into a cache indexed by volume handle, started on first use.
+// A nil pvLookup finds nothing.
+type pvLookup struct {
+	informer cache.SharedIndexInformer
+	start    sync.Once
+}
+
+func newPVLookup(client kubernetes.Interface) *pvLookup {
+	return &pvLookup{
+		informer: coreinformers.NewPersistentVolumeInformer(client, 0, cache.Indexers{volumeHandleIndex: volumeHandleIndexFunc}),
+	}
+}
+
+func volumeHandleIndexFunc(obj interface{}) ([]string, error) {
+	pv, ok := obj.(*corev1.PersistentVolume)
+	if !ok || pv.Spec.CSI == nil || pv.Spec.CSI.Driver != driverName {
+		return nil, nil
+	}
+	return []string{pv.Spec.CSI.VolumeHandle}, nil
+}
+
+// get returns the PersistentVolume of volumeId, or nil if there is none. It fails if the PVs could not be listed
+// before ctx is done. The returned PV is shared with the cache and must not be modified.
+func (l *pvLookup) get(ctx context.Context, volumeId string) (*corev1.PersistentVolume, error) {
+	if l == nil {
+		return nil, nil
+	}
+	if err := l.sync(ctx); err != nil {
+		return nil, err
+	}
+	objs, err := l.informer.GetIndexer().ByIndex(volumeHandleIndex, volumeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs) == 0 {
+		return nil, nil
+	}
+	return objs[0].(*corev1.PersistentVolume), nil
+}
+
+// list returns the PersistentVolumes of the driver. They are shared with the cache and must not be modified.
+func (l *pvLookup) list(ctx context.Context) ([]*corev1.PersistentVolume, error) {
+	if l == nil {
+		return nil, nil
+	}
+	if err := l.sync(ctx); err != nil {
+		return nil, err
+	}
+	var pvs []*corev1.PersistentVolume
+	for _, obj := range l.informer.GetStore().List() {
+		if handles, _ := volumeHandleIndexFunc(obj); len(handles) > 0 {
+			pvs = append(pvs, obj.(*corev1.PersistentVolume))
+		}
+	}
+	return pvs, nil
+}
+
+// sync starts the watch on first use and waits until the PVs are listed.
+func (l *pvLookup) sync(ctx context.Context) error {
+	l.start.Do(func() {
+		go l.informer.Run(wait.NeverStop)
+	})
+	if !cache.WaitForCacheSync(ctx.Done(), l.informer.HasSynced) {
+		return fmt.Errorf("PersistentVolumes were not listed in time: %v", ctx.Err())
+	}
+	return nil
+}
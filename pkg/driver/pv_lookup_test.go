@@ -0,0 +1,82 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPVLookup(t *testing.T) {
+	pv := func(name, driver, volumeHandle string) *corev1.PersistentVolume {
+		return &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driver, VolumeHandle: volumeHandle},
+				},
+			},
+		}
+	}
+	client := fake.NewSimpleClientset(
+		pv("pv-ebs", "ebs.csi.aws.com", "fs-abcd1234::fsap-other"),
+		pv("pv-efs", driverName, "fs-abcd1234::fsap-abcd1234xyz987"),
+		&corev1.PersistentVolume{ObjectMeta: metav1.ObjectMeta{Name: "pv-nfs"}},
+	)
+	l := newPVLookup(client)
+	ctx := context.Background()
+
+	found, err := l.get(ctx, "fs-abcd1234::fsap-abcd1234xyz987")
+	if err != nil || found == nil || found.Name != "pv-efs" {
+		t.Fatalf("Expected PV pv-efs, got %v, %v", found, err)
+	}
+	// The PVs of other drivers are not indexed.
+	if found, err := l.get(ctx, "fs-abcd1234::fsap-other"); err != nil || found != nil {
+		t.Fatalf("Expected no PV, got %v, %v", found, err)
+	}
+
+	// Only the PVs of the driver are listed.
+	if pvs, err := l.list(ctx); err != nil || len(pvs) != 1 || pvs[0].Name != "pv-efs" {
+		t.Fatalf("Expected to list PV pv-efs, got %v, %v", pvs, err)
+	}
+
+	var nilLookup *pvLookup
+	if found, err := nilLookup.get(ctx, "fs-abcd1234::fsap-abcd1234xyz987"); err != nil || found != nil {
+		t.Fatalf("Expected nil lookup to find nothing, got %v, %v", found, err)
+	}
+}
+
+func TestPVLookupNotSynced(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+	l := newPVLookup(client)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := l.get(ctx, "fs-abcd1234"); err == nil {
+		t.Fatalf("Expected lookup to fail once ctx is done")
+	}
+}
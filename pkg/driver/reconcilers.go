@@ -0,0 +1,109 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// reconcilerLeaseName is the Lease the controller replicas elect the one running the reconcilers with.
+	// It is separate from the Lease of the external-provisioner, which is held by another container.
+	reconcilerLeaseName = "efs-csi-aws-com-reconciler"
+
+	leaderElectionLeaseDuration = 15 * time.Second
+	leaderElectionRenewDeadline = 10 * time.Second
+	leaderElectionRetryPeriod   = 2 * time.Second
+)
+
+// startReconcilers runs the enabled reconcilers on the elected leader of the controller replicas.
+func (d *Driver) startReconcilers() error {
+	if d.orphanedAccessPointReconcileInterval <= 0 && d.orphanedRootDirScanInterval <= 0 && d.clusterId == "" {
+		return nil
+	}
+	// The reconcilers record Events, and the event recorder needs the client too.
+	if d.kubeClient == nil {
+		return errors.New("reconcilers need access to the Kubernetes API")
+	}
+	var reconcilers []func(ctx context.Context)
+	if d.orphanedAccessPointReconcileInterval > 0 {
+		reconcilers = append(reconcilers, d.newOrphanedAccessPointReconciler().run)
+	}
+	if d.orphanedRootDirScanInterval > 0 {
+		reconcilers = append(reconcilers, d.newOrphanedRootDirScanner().run)
+	}
+	if d.clusterId != "" {
+		reconcilers = append(reconcilers, d.newOwnershipReconciler().run)
+	}
+
+	go runLeaderElected(context.Background(), d.kubeClient, d.leaderElectionNamespace, reconcilerLeaseName, func(ctx context.Context) {
+		for _, run := range reconcilers {
+			go run(ctx)
+		}
+		<-ctx.Done()
+	})
+	return nil
+}
+
+// runLeaderElected runs run while this replica holds the Lease name in namespace, until ctx is done. ctx passed
+// to run is cancelled when the Lease is lost.
+func runLeaderElected(ctx context.Context, client kubernetes.Interface, namespace, name string, run func(ctx context.Context)) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		klog.Warningf("Could not get hostname for leader election identity: %v", err)
+	}
+	identity := hostname + "_" + uuid.New().String()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+	for ctx.Err() == nil {
+		leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			ReleaseOnCancel: true,
+			LeaseDuration:   leaderElectionLeaseDuration,
+			RenewDeadline:   leaderElectionRenewDeadline,
+			RetryPeriod:     leaderElectionRetryPeriod,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(ctx context.Context) {
+					klog.Infof("Became leader of Lease %s/%s", namespace, name)
+					run(ctx)
+				},
+				OnStoppedLeading: func() {
+					klog.Infof("Stopped leading Lease %s/%s", namespace, name)
+				},
+			},
+		})
+	}
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartReconcilersWithoutKubeClient(t *testing.T) {
+	testCases := []struct {
+		name        string
+		driver      *Driver
+		expectError bool
+	}{
+		{
+			name:   "no reconciler enabled",
+			driver: &Driver{},
+		},
+		{
+			name:        "orphaned access point reconciler",
+			driver:      &Driver{orphanedAccessPointReconcileInterval: time.Minute},
+			expectError: true,
+		},
+		{
+			name:        "orphaned root directory scanner",
+			driver:      &Driver{orphanedRootDirScanInterval: time.Minute},
+			expectError: true,
+		},
+		{
+			name:        "ownership reconciler",
+			driver:      &Driver{clusterId: "cluster"},
+			expectError: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.driver.startReconcilers()
+			if !tc.expectError {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != "reconcilers need access to the Kubernetes API" {
+				t.Fatalf("Expected missing Kubernetes API error, got %v", err)
+			}
+		})
+	}
+}
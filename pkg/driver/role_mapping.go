@@ -0,0 +1,105 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// roleMapping maps the namespaces of PVCs and the file systems of volumes to the IAM roles the controller assumes
+// to provision and delete them, instead of the awsRoleArn of the StorageClass secrets. The role of the namespace
+// takes precedence over the role of the file system, so that a namespace cannot use the role of another tenant by
+// picking its file system.
+type roleMapping struct {
+	Namespaces  map[string]roleMappingEntry `json:"namespaces,omitempty"`
+	FileSystems map[string]roleMappingEntry `json:"fileSystems,omitempty"`
+}
+
+type roleMappingEntry struct {
+	RoleArn string `json:"roleArn"`
+	// CrossAccount resolves the mount targets of the file system with DNS, like the crossaccount secret.
+	CrossAccount bool `json:"crossAccount,omitempty"`
+}
+
+// loadRoleMapping reads the role mapping from file. There is no mapping if file is empty.
+func loadRoleMapping(file string) (*roleMapping, error) {
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role mapping: %v", err)
+	}
+	mapping := &roleMapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse role mapping %s: %v", file, err)
+	}
+	for kind, entries := range map[string]map[string]roleMappingEntry{"namespace": mapping.Namespaces, "file system": mapping.FileSystems} {
+		for key, entry := range entries {
+			if !strings.HasPrefix(entry.RoleArn, "arn:") {
+				return nil, fmt.Errorf("invalid roleArn %q of %s %s in role mapping %s", entry.RoleArn, kind, key, file)
+			}
+		}
+	}
+	klog.Infof("Loaded role mapping of %d namespaces and %d file systems from %s", len(mapping.Namespaces), len(mapping.FileSystems), file)
+	return mapping, nil
+}
+
+// lookup returns the role mapped to namespace or else to fileSystemId, or nil if there is none.
+func (m *roleMapping) lookup(namespace, fileSystemId string) *roleMappingEntry {
+	if m == nil {
+		return nil
+	}
+	if entry, ok := m.Namespaces[namespace]; ok && namespace != "" {
+		return &entry
+	}
+	if entry, ok := m.FileSystems[fileSystemId]; ok && fileSystemId != "" {
+		return &entry
+	}
+	return nil
+}
+
+// volumeNamespace returns the namespace of the PVC bound to the PersistentVolume of volumeId, which DeleteVolume
+// is not given. It is empty if the namespaces of the role mapping are not used. Otherwise it fails if the namespace
+// cannot be found, rather than deleting the volume with another role, which may not find it.
+func (d *Driver) volumeNamespace(ctx context.Context, volumeId string) (string, error) {
+	if d.roleMapping == nil || len(d.roleMapping.Namespaces) == 0 {
+		return "", nil
+	}
+	if d.pvs == nil {
+		return "", status.Errorf(codes.FailedPrecondition, "The namespaces of the role mapping require access to the Kubernetes API")
+	}
+	pv, err := d.pvs.get(ctx, volumeId)
+	if err != nil {
+		return "", status.Errorf(codes.Unavailable, "Could not find the PersistentVolume of volume %s for the role mapping: %v", volumeId, err)
+	}
+	if pv == nil {
+		return "", status.Errorf(codes.FailedPrecondition, "Could not find the PersistentVolume of volume %s for the role mapping", volumeId)
+	}
+	if pv.Spec.ClaimRef == nil {
+		return "", status.Errorf(codes.FailedPrecondition, "PersistentVolume %s of volume %s is not bound to a PVC, its namespace is needed for the role mapping", pv.Name, volumeId)
+	}
+	return pv.Spec.ClaimRef.Namespace, nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+)
+
+const (
+	teamARole = "arn:aws:iam::111122223333:role/efs-team-a"
+	fsBRole   = "arn:aws:iam::444455556666:role/efs-fs-b"
+)
+
+func TestLoadRoleMapping(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mapping   string
+		expectErr bool
+	}{
+		{
+			name:    "valid mapping",
+			mapping: "namespaces:\n  team-a:\n    roleArn: " + teamARole + "\n    crossAccount: true\nfileSystems:\n  fs-b:\n    roleArn: " + fsBRole + "\n",
+		},
+		{
+			name:      "missing role",
+			mapping:   "namespaces:\n  team-a:\n    crossAccount: true\n",
+			expectErr: true,
+		},
+		{
+			name:      "unknown field",
+			mapping:   "namespace:\n  team-a:\n    roleArn: " + teamARole + "\n",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "roles.yaml")
+			if err := os.WriteFile(file, []byte(tc.mapping), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadRoleMapping(file)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRoleMappingLookup(t *testing.T) {
+	mapping := &roleMapping{
+		Namespaces:  map[string]roleMappingEntry{"team-a": {RoleArn: teamARole, CrossAccount: true}},
+		FileSystems: map[string]roleMappingEntry{"fs-b": {RoleArn: fsBRole}},
+	}
+	testCases := []struct {
+		name         string
+		mapping      *roleMapping
+		namespace    string
+		fileSystemId string
+		expected     string
+	}{
+		{name: "namespace", mapping: mapping, namespace: "team-a", fileSystemId: "fs-a", expected: teamARole},
+		{name: "namespace before file system", mapping: mapping, namespace: "team-a", fileSystemId: "fs-b", expected: teamARole},
+		{name: "file system", mapping: mapping, namespace: "team-b", fileSystemId: "fs-b", expected: fsBRole},
+		{name: "not mapped", mapping: mapping, namespace: "team-b", fileSystemId: "fs-a"},
+		{name: "no mapping", namespace: "team-a", fileSystemId: "fs-b"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var role string
+			if entry := tc.mapping.lookup(tc.namespace, tc.fileSystemId); entry != nil {
+				role = entry.RoleArn
+			}
+			if role != tc.expected {
+				t.Fatalf("Expected role %q, got %q", tc.expected, role)
+			}
+		})
+	}
+}
+
+func TestGetCloudWithRoleMapping(t *testing.T) {
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+	mockCloud := mocks.NewMockCloud(mockCtl)
+	driver := &Driver{
+		cloud: mockCloud,
+		roleMapping: &roleMapping{
+			Namespaces: map[string]roleMappingEntry{"team-a": {RoleArn: teamARole}},
+		},
+	}
+
+	// A role in the secrets cannot replace the mapped role.
+	_, _, _, err := getCloud(map[string]string{RoleArn: fsBRole}, driver, "team-a", "fs-a")
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Expected PermissionDenied error, got %v", err)
+	}
+
+	// Volumes that are not mapped use the cloud of the driver.
+	localCloud, roleArn, _, err := getCloud(map[string]string{}, driver, "team-b", "fs-a")
+	if err != nil || localCloud != mockCloud || roleArn != "" {
+		t.Fatalf("Expected the cloud of the driver, got %v, %q, %v", localCloud, roleArn, err)
+	}
+}
+
+func TestVolumeNamespace(t *testing.T) {
+	volumeId := "fs-abcd1234::fsap-abcd1234xyz987"
+	driver := &Driver{
+		roleMapping: &roleMapping{
+			Namespaces: map[string]roleMappingEntry{"team-a": {RoleArn: teamARole}},
+		},
+		pvs: newPVLookup(fake.NewSimpleClientset(&corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: volumeId},
+				},
+				ClaimRef: &corev1.ObjectReference{Namespace: "team-a", Name: "pvc"},
+			},
+		}, &corev1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "pv-unbound"},
+			Spec: corev1.PersistentVolumeSpec{
+				PersistentVolumeSource: corev1.PersistentVolumeSource{
+					CSI: &corev1.CSIPersistentVolumeSource{Driver: driverName, VolumeHandle: "fs-abcd1234::fsap-unbound"},
+				},
+			},
+		})),
+	}
+
+	ctx := context.Background()
+	if namespace, err := driver.volumeNamespace(ctx, volumeId); err != nil || namespace != "team-a" {
+		t.Fatalf("Expected namespace team-a, got %q, %v", namespace, err)
+	}
+	if namespace, err := driver.volumeNamespace(ctx, "fs-abcd1234::fsap-unbound"); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition for unbound volume, got %q, %v", namespace, err)
+	}
+	if namespace, err := driver.volumeNamespace(ctx, "fs-abcd1234::fsap-other"); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition for unknown volume, got %q, %v", namespace, err)
+	}
+
+	// Without the namespaces of the role mapping, the PV is not looked up.
+	driver.roleMapping = &roleMapping{FileSystems: map[string]roleMappingEntry{"fs-abcd1234": {RoleArn: teamARole}}}
+	if namespace, err := driver.volumeNamespace(ctx, "fs-abcd1234::fsap-other"); err != nil || namespace != "" {
+		t.Fatalf("Expected no namespace, got %q, %v", namespace, err)
+	}
+
+	driver = &Driver{roleMapping: &roleMapping{Namespaces: map[string]roleMappingEntry{"team-a": {RoleArn: teamARole}}}}
+	if namespace, err := driver.volumeNamespace(ctx, volumeId); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition without Kubernetes API, got %q, %v", namespace, err)
+	}
+}
+
+func TestVolumeNamespaceUnavailable(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "persistentvolumes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+	driver := &Driver{
+		roleMapping: &roleMapping{
+			Namespaces: map[string]roleMappingEntry{"team-a": {RoleArn: teamARole}},
+		},
+		pvs: newPVLookup(client),
+	}
+
+	// DeleteVolume fails to be retried, rather than using the role of another account.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := driver.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: "fs-abcd1234::fsap-abcd1234xyz987"})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("Expected Unavailable, got %v", err)
+	}
+}
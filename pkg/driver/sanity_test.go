@@ -37,9 +37,17 @@ func (w *mockWatchdog) start() error {
 	return nil
 }
 
+func (w *mockWatchdog) setup(efsClientSource string) error {
+	return nil
+}
+
 func (w *mockWatchdog) stop() {
 }
 
+func (w *mockWatchdog) healthy() error {
+	return nil
+}
+
 func TestSanityEFSCSI(t *testing.T) {
 	// Setup the full driver and its environment
 	dir, err := ioutil.TempDir("", "sanity-efs-csi")
@@ -78,7 +86,7 @@ func TestSanityEFSCSI(t *testing.T) {
 		cloud:           mockCloud,
 		nodeCaps:        nodeCaps,
 		volMetricsOptIn: true,
-		volStatter:      NewVolStatter(),
+		volStatter:      NewVolStatter(1),
 		gidAllocator:    NewGidAllocator(),
 	}
 	defer func() {
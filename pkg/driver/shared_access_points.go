@@ -0,0 +1,156 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/klog/v2"
+
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/cloud"
+)
+
+const (
+	// SharedAccessPointNameTagKey is set on access points shared by the volumes of a sharedAccessPointName.
+	SharedAccessPointNameTagKey = "efs.csi.aws.com/shared-access-point-name"
+	// sharedAccessPointConsumerTagPrefix is the prefix of the tags recording the volumes using a shared access point.
+	sharedAccessPointConsumerTagPrefix = "efs.csi.aws.com/consumer/"
+	// maxAccessPointTags is the number of tags EFS allows on an access point.
+	maxAccessPointTags = 50
+	// maxTagKeyLength is the length of tag keys EFS allows.
+	maxTagKeyLength = 128
+)
+
+// sharedAccessPointLocks serializes the creation and deletion of the volumes of each shared access point, so that
+// the last consumer of an access point is not removed while another volume starts using it. The zero value is
+// ready to use.
+type sharedAccessPointLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock locks the shared access point key, and returns the function unlocking it.
+func (l *sharedAccessPointLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*sync.Mutex{}
+	}
+	keyLock, ok := l.locks[key]
+	if !ok {
+		keyLock = &sync.Mutex{}
+		l.locks[key] = keyLock
+	}
+	l.mu.Unlock()
+
+	keyLock.Lock()
+	return keyLock.Unlock
+}
+
+// sharedAccessPointClientToken is the client token of the access point shared by the volumes of name on a file
+// system, so that CreateVolume can find it.
+func sharedAccessPointClientToken(fileSystemId, name string) string {
+	return get64LenHash("shared:" + fileSystemId + ":" + name)
+}
+
+// sharedAccessPointConsumerTagKey is the tag recording that volName uses a shared access point. Volume names too
+// long for a tag key are hashed.
+func sharedAccessPointConsumerTagKey(volName string) string {
+	key := sharedAccessPointConsumerTagPrefix + volName
+	if len(key) > maxTagKeyLength {
+		key = sharedAccessPointConsumerTagPrefix + get64LenHash(volName)
+	}
+	return key
+}
+
+// sharedAccessPointConsumers counts the consumer tags of a shared access point.
+func sharedAccessPointConsumers(tags map[string]string) int {
+	consumers := 0
+	for key := range tags {
+		if strings.HasPrefix(key, sharedAccessPointConsumerTagPrefix) {
+			consumers++
+		}
+	}
+	return consumers
+}
+
+// sharedAccessPointConsumer returns the volume name in the optional fourth field of the ID of a volume using a
+// shared access point, fs-id::fsap-id:volume-name.
+func sharedAccessPointConsumer(volumeId string) string {
+	tokens := strings.Split(volumeId, ":")
+	if len(tokens) == 4 {
+		return tokens[3]
+	}
+	return ""
+}
+
+// addSharedAccessPointConsumer records that volName uses the shared accessPoint, unless it already does because
+// CreateVolume is retried.
+func (d *Driver) addSharedAccessPointConsumer(ctx context.Context, localCloud cloud.Cloud, accessPoint *cloud.AccessPoint, volName string, volumeParams map[string]string) error {
+	key := sharedAccessPointConsumerTagKey(volName)
+	if _, ok := accessPoint.Tags[key]; ok {
+		return nil
+	}
+	// Each consumer takes a tag, so the tags of the access point bound the number of volumes sharing it.
+	if len(accessPoint.Tags) >= maxAccessPointTags {
+		return d.provisioningFailed(ctx, volumeParams, ReasonSharedAccessPointTagLimitReached,
+			status.Errorf(codes.ResourceExhausted, "Shared Access Point %v has %d tags, the most EFS allows, so no more volumes can use it: it is used by %d volumes, use another %v for new volumes",
+				accessPoint.AccessPointId, len(accessPoint.Tags), sharedAccessPointConsumers(accessPoint.Tags), SharedAccessPointName))
+	}
+
+	value := "true"
+	if volumeParams[PvcNamespace] != "" && volumeParams[PvcName] != "" {
+		value = volumeParams[PvcNamespace] + "/" + volumeParams[PvcName]
+	}
+	err := localCloud.TagAccessPoint(ctx, accessPoint.AccessPointId, map[string]string{key: value})
+	if err != nil {
+		if err == cloud.ErrAccessDenied {
+			return d.provisioningFailed(ctx, volumeParams, ReasonAccessDenied,
+				status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
+		}
+		if err == cloud.ErrNotFound {
+			return status.Errorf(codes.Aborted, "Shared Access Point %v was deleted while provisioning the volume", accessPoint.AccessPointId)
+		}
+		return status.Errorf(codes.Internal, "Failed to tag shared Access Point %v: %v", accessPoint.AccessPointId, err)
+	}
+	klog.V(2).Infof("Volume %v uses shared Access Point %v", volName, accessPoint.AccessPointId)
+	return nil
+}
+
+// removeSharedAccessPointConsumer removes the tag recording that consumer uses a shared access point, and returns
+// the number of volumes still using it.
+func (d *Driver) removeSharedAccessPointConsumer(ctx context.Context, localCloud cloud.Cloud, volId, accessPointId, consumer string) (int, error) {
+	err := localCloud.UntagAccessPoint(ctx, accessPointId, []string{sharedAccessPointConsumerTagKey(consumer)})
+	if err == nil {
+		var accessPoint *cloud.AccessPoint
+		accessPoint, err = localCloud.DescribeAccessPoint(ctx, accessPointId)
+		if err == nil {
+			return sharedAccessPointConsumers(accessPoint.Tags), nil
+		}
+	}
+	if err == cloud.ErrAccessDenied {
+		return 0, d.deletionFailed(ctx, volId, ReasonAccessDenied,
+			status.Errorf(codes.Unauthenticated, "Access Denied. Please ensure you have the right AWS permissions: %v", err))
+	}
+	if err == cloud.ErrNotFound {
+		return 0, nil
+	}
+	return 0, status.Errorf(codes.Internal, "Failed to remove volume %v from shared Access Point %v: %v", volId, accessPointId, err)
+}
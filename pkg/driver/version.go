@@ -35,6 +35,8 @@ type VersionInfo struct {
 	GoVersion       string `json:"goVersion"`
 	Compiler        string `json:"compiler"`
 	Platform        string `json:"platform"`
+	// FeatureGates are the enabled feature gates.
+	FeatureGates []string `json:"featureGates,omitempty"`
 }
 
 func GetVersion() VersionInfo {
@@ -46,6 +48,7 @@ func GetVersion() VersionInfo {
 		GoVersion:       runtime.Version(),
 		Compiler:        runtime.Compiler,
 		Platform:        fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+		FeatureGates:    enabledFeatureGates(),
 	}
 }
 func GetVersionJSON() (string, error) {
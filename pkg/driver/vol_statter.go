@@ -15,10 +15,11 @@ limitations under the License.
 package driver
 
 import (
+	"context"
+
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
-	"k8s.io/kubernetes/pkg/volume/util/fs"
 	"sync"
 	"time"
 )
@@ -31,23 +32,34 @@ type volMetrics struct {
 
 var (
 	volUsageCache        = make(map[string]*volMetrics)
-	volStatterJobTracker = make(map[string]bool)
+	volStatterJobTracker = make(map[string]*volStatterJob)
 	fsRateLimiter        = make(map[string]int)
 	mu                   sync.RWMutex
 	jitter               = time.Duration(5 * time.Minute)
 )
 
+// volStatterJob is a running computation of the usage of a volume.
+type volStatterJob struct {
+	cancel context.CancelFunc
+}
+
 type VolStatter interface {
 	computeVolumeMetrics(volId, volPath string, refreshRate float64, fsRateLimit int) (*volMetrics, error)
 	retrieveFromCache(volId string) (*volMetrics, bool)
 	removeFromCache(volId string)
+	// cancelComputation stops the computation of the usage of volId, which keeps its volume busy.
+	cancelComputation(volId string)
 }
 
 type VolStatterImpl struct {
+	// walkConcurrency is the number of directories of a volume read at once to compute its usage.
+	walkConcurrency int
+	// statfs is statFS, replaced by tests.
+	statfs func(path string) (fsStats, error)
 }
 
-func NewVolStatter() VolStatter {
-	return &VolStatterImpl{}
+func NewVolStatter(walkConcurrency int) VolStatter {
+	return &VolStatterImpl{walkConcurrency: walkConcurrency, statfs: statFS}
 }
 
 func (v VolStatterImpl) computeVolumeMetrics(volId, volPath string, refreshRate float64, fsRateLimit int) (*volMetrics, error) {
@@ -63,16 +75,20 @@ func (v VolStatterImpl) computeVolumeMetrics(volId, volPath string, refreshRate
 
 	v.launchVolStatsRoutine(volId, volPath, fsRateLimit)
 
-	// Return nil as kubelet might timeout waiting for volume stats
-	klog.Warningf("Volume metrics computation is underway for Vol ID: %v and metrics are not available yet.", volId)
+	// Don't wait for the walk as kubelet might timeout waiting for volume stats, but report what statfs returns right
+	// away. The volume would be reported empty until the walk finishes, so the usage of its whole file system, which
+	// bounds that of the volume, is reported instead.
+	usage := []*csi.VolumeUsage{{Unit: csi.VolumeUsage_UNKNOWN}}
+	if stats, err := v.statfs(volPath); err != nil {
+		klog.Warningf("Volume metrics computation is underway for Vol ID: %v and metrics are not available yet: %v", volId, err)
+	} else {
+		klog.V(4).Infof("Volume metrics computation is underway for Vol ID: %v, reporting the usage of its file system until it is done", volId)
+		usage = volumeUsage(diskUsage{bytes: stats.capacity - stats.available, inodes: stats.inodes - stats.inodesFree}, stats)
+	}
 	return &volMetrics{
 		volPath:   volPath,
 		timeStamp: time.Now(),
-		volUsage: []*csi.VolumeUsage{
-			{
-				Unit: csi.VolumeUsage_UNKNOWN,
-			},
-		},
+		volUsage:  usage,
 	}, nil
 }
 
@@ -92,6 +108,17 @@ func (v VolStatterImpl) removeFromCache(volId string) {
 	mu.Unlock()
 }
 
+func (v VolStatterImpl) cancelComputation(volId string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if job, ok := volStatterJobTracker[volId]; ok {
+		klog.V(4).Infof("Cancelling volume stats computation for vol ID: %v", volId)
+		job.cancel()
+	}
+}
+
+// launchVolStatsRoutine walks volPath after a random delay, so that the volumes published or refreshed at once, e.g.
+// after a restart of the node plugin, are not all walked at once.
 func (v VolStatterImpl) launchVolStatsRoutine(volId, volPath string, fsRateLimit int) {
 	fsId, _, _, err := parseVolumeId(volId)
 	if err != nil {
@@ -104,8 +131,10 @@ func (v VolStatterImpl) launchVolStatsRoutine(volId, volPath string, fsRateLimit
 		klog.V(5).Infof("Volume stats computation job is underway for volume Id : %v. Awaiting results", volId)
 	} else {
 		if ok := canStatFS(fsId, fsRateLimit); ok {
-			volStatterJobTracker[volId] = true
-			go v.computeDiskUsage(fsId, volId, volPath)
+			ctx, cancel := context.WithCancel(context.Background())
+			job := &volStatterJob{cancel: cancel}
+			volStatterJobTracker[volId] = job
+			go v.computeDiskUsage(ctx, job, fsId, volId, volPath, wait.Jitter(jitter, 2.0))
 		} else {
 			klog.V(5).Infof("Too many stat routines are running against FS : %s. Retry stat for volume Id: %s later", fsId, volId)
 		}
@@ -113,49 +142,81 @@ func (v VolStatterImpl) launchVolStatsRoutine(volId, volPath string, fsRateLimit
 	mu.Unlock()
 }
 
-func (v VolStatterImpl) computeDiskUsage(fsId, volId, volPath string) {
-	waitTime := wait.Jitter(jitter, 2.0)
-	klog.V(5).Infof("Compute Volume Metrics invoked for Vol ID: %v, Sleeping for %v before execution", volId, waitTime)
+func (v VolStatterImpl) computeDiskUsage(ctx context.Context, job *volStatterJob, fsId, volId, volPath string, delay time.Duration) {
+	defer finishComputation(job, fsId, volId)
+	klog.V(5).Infof("Compute Volume Metrics invoked for Vol ID: %v, Sleeping for %v before execution", volId, delay)
 
 	//jittered execution
-	time.Sleep(waitTime)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		klog.V(4).Infof("Volume stats computation cancelled for vol ID: %v", volId)
+		return
+	}
 
-	used, err := fs.DiskUsage(volPath)
+	start := time.Now()
+	used, err := walkDiskUsage(ctx, volPath, v.walkConcurrency)
 	if err != nil {
+		if ctx.Err() != nil {
+			volumeUsageWalkDuration.WithLabelValues("cancelled").Observe(time.Since(start).Seconds())
+			klog.V(4).Infof("Volume stats computation cancelled for vol ID: %v", volId)
+			return
+		}
+		volumeUsageWalkDuration.WithLabelValues("failure").Observe(time.Since(start).Seconds())
 		klog.Errorf("Failed to compute volume usage on path %s: %v", volPath, err)
 		return
 	}
+	volumeUsageWalkDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
+	klog.V(5).Infof("Computed the usage of vol ID: %v in %v", volId, time.Since(start))
 
-	volUsed := used.Bytes
-
-	available, capacity, _, _, _, _, err := fs.Info(volPath)
+	stats, err := v.statfs(volPath)
 	if err != nil {
 		klog.Errorf("Failed to fetch FsInfo on volume path %s: %v", volPath, err)
 		return
 	}
 
-	usage := []*csi.VolumeUsage{
+	volMetrics := &volMetrics{
+		volPath:   volPath,
+		timeStamp: time.Now(),
+		volUsage:  volumeUsage(used, stats)}
+
+	mu.Lock()
+	// The usage of a volume unmounted meanwhile is not cached.
+	if ctx.Err() == nil {
+		volUsageCache[volId] = volMetrics
+	}
+	mu.Unlock()
+}
+
+// volumeUsage reports the space and the inodes used by a volume, and those available on its file system.
+func volumeUsage(used diskUsage, stats fsStats) []*csi.VolumeUsage {
+	return []*csi.VolumeUsage{
 		{
 			Unit:      csi.VolumeUsage_BYTES,
-			Used:      volUsed,
-			Available: available,
-			Total:     capacity,
+			Used:      used.bytes,
+			Available: stats.available,
+			Total:     stats.capacity,
+		},
+		{
+			Unit:      csi.VolumeUsage_INODES,
+			Used:      used.inodes,
+			Available: stats.inodesFree,
+			Total:     stats.inodes,
 		},
 	}
+}
 
-	volMetrics := &volMetrics{
-		volPath:   volPath,
-		timeStamp: time.Now(),
-		volUsage:  usage}
-
+// finishComputation lets the usage of volId be computed again, and another computation run on fsId.
+func finishComputation(job *volStatterJob, fsId, volId string) {
 	mu.Lock()
-	volUsageCache[volId] = volMetrics
-	delete(volStatterJobTracker, volId)
+	defer mu.Unlock()
+	job.cancel()
+	if volStatterJobTracker[volId] == job {
+		delete(volStatterJobTracker, volId)
+	}
 	if count, ok := fsRateLimiter[fsId]; ok && count > 0 {
 		fsRateLimiter[fsId] = count - 1
 	}
-	mu.Unlock()
-	return
 }
 
 func canStatFS(fsId string, fsRateLimit int) bool {
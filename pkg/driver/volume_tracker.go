@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+	mount_utils "k8s.io/mount-utils"
+)
+
+// volumeTracker tracks the target paths each volume is published at on the node. It persists them to file, so that
+// the node plugin still knows the volumes published before it restarted. Without the file, they are found again in
+// the mountinfo at mountInfoPath under kubeletDir. The zero value tracks the volumes in memory only.
+type volumeTracker struct {
+	mu            sync.Mutex
+	file          string
+	mountInfoPath string
+	kubeletDir    string
+	targets       map[string]map[string]bool
+}
+
+// csiVolumeData is the part of the vol_data.json file kubelet keeps next to the target path of a CSI volume that
+// identifies the volume.
+type csiVolumeData struct {
+	DriverName   string `json:"driverName"`
+	VolumeHandle string `json:"volumeHandle"`
+}
+
+// volumeTrackerState is the content of the file of a volumeTracker.
+type volumeTrackerState struct {
+	// Volumes are the target paths of the published volumes, by volume ID.
+	Volumes map[string][]string `json:"volumes"`
+}
+
+// load restores the volumes from the file of the tracker. The targets that are no longer mounted, e.g. after a
+// reboot of the node, are dropped. Without the file, the volumes are rebuilt from the mounts.
+func (t *volumeTracker) load(mounter Mounter) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == "" {
+		return t.rebuildLocked()
+	}
+	data, err := os.ReadFile(t.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return t.rebuildLocked()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read volume state: %v", err)
+	}
+	var state volumeTrackerState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse volume state %s: %v", t.file, err)
+	}
+
+	t.targets = map[string]map[string]bool{}
+	for volumeId, targets := range state.Volumes {
+		for _, target := range targets {
+			_, refCount, err := mounter.GetDeviceName(target)
+			if err != nil {
+				klog.Warningf("Could not check if volume %s is still mounted at %s, keeping it: %v", volumeId, target, err)
+			} else if refCount == 0 {
+				klog.V(4).Infof("Volume %s is no longer mounted at %s", volumeId, target)
+				continue
+			}
+			if t.targets[volumeId] == nil {
+				t.targets[volumeId] = map[string]bool{}
+			}
+			t.targets[volumeId][target] = true
+		}
+	}
+	klog.Infof("Restored %d published volumes from %s", len(t.targets), t.file)
+	t.saveLocked()
+	return nil
+}
+
+// rebuildLocked finds the volumes published by the driver in the NFS mounts at the target paths kubelet gives to CSI
+// volumes, <kubeletDir>/pods/<pod UID>/volumes/kubernetes.io~csi/<PV name>/mount, with the vol_data.json file
+// next to them. It lets the node plugin find the volumes it published before their state was persisted.
+func (t *volumeTracker) rebuildLocked() error {
+	if t.mountInfoPath == "" || t.kubeletDir == "" {
+		return nil
+	}
+	infos, err := mount_utils.ParseMountInfo(t.mountInfoPath)
+	if err != nil {
+		return fmt.Errorf("failed to list mounts: %v", err)
+	}
+	podsDir := filepath.Join(t.kubeletDir, "pods") + string(filepath.Separator)
+	t.targets = map[string]map[string]bool{}
+	for _, info := range infos {
+		target := info.MountPoint
+		if !strings.HasPrefix(info.FsType, "nfs") || !strings.HasPrefix(target, podsDir) || filepath.Base(target) != "mount" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(target), "vol_data.json"))
+		if err != nil {
+			klog.V(4).Infof("Not tracking %s, could not read its volume data: %v", target, err)
+			continue
+		}
+		var volume csiVolumeData
+		if err := json.Unmarshal(data, &volume); err != nil {
+			klog.Warningf("Not tracking %s, could not parse its volume data: %v", target, err)
+			continue
+		}
+		if volume.DriverName != driverName || volume.VolumeHandle == "" {
+			continue
+		}
+		if t.targets[volume.VolumeHandle] == nil {
+			t.targets[volume.VolumeHandle] = map[string]bool{}
+		}
+		t.targets[volume.VolumeHandle][target] = true
+	}
+	klog.Infof("Rebuilt %d published volumes from %s", len(t.targets), t.mountInfoPath)
+	t.saveLocked()
+	return nil
+}
+
+// add tracks volumeId as published at target.
+func (t *volumeTracker) add(volumeId, target string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.targets == nil {
+		t.targets = map[string]map[string]bool{}
+	}
+	if t.targets[volumeId] == nil {
+		t.targets[volumeId] = map[string]bool{}
+	}
+	if t.targets[volumeId][target] {
+		return
+	}
+	t.targets[volumeId][target] = true
+	t.saveLocked()
+}
+
+// remove stops tracking volumeId at target, and tells whether it was the last target of the volume.
+func (t *volumeTracker) remove(volumeId, target string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	targets, ok := t.targets[volumeId]
+	if !ok || !targets[target] {
+		return false
+	}
+	delete(targets, target)
+	if len(targets) == 0 {
+		delete(t.targets, volumeId)
+	}
+	t.saveLocked()
+	return len(targets) == 0
+}
+
+// published returns the targets of the published volumes, by volume ID.
+func (t *volumeTracker) published() map[string][]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	published := map[string][]string{}
+	for volumeId, targets := range t.targets {
+		published[volumeId] = sortedTargets(targets)
+	}
+	return published
+}
+
+// saveLocked reports the published volumes, and writes them to the file of the tracker. Failing to write them is
+// logged, as it only affects the node plugin after a restart.
+func (t *volumeTracker) saveLocked() {
+	publishedVolumes.Set(float64(len(t.targets)))
+	if t.file == "" {
+		return
+	}
+	state := volumeTrackerState{Volumes: map[string][]string{}}
+	for volumeId, targets := range t.targets {
+		state.Volumes[volumeId] = sortedTargets(targets)
+	}
+	data, err := json.Marshal(state)
+	if err == nil {
+		err = writeFileAtomically(t.file, data)
+	}
+	if err != nil {
+		klog.Warningf("Could not save volume state to %s: %v", t.file, err)
+	}
+}
+
+func sortedTargets(targets map[string]bool) []string {
+	sorted := make([]string, 0, len(targets))
+	for target := range targets {
+		sorted = append(sorted, target)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
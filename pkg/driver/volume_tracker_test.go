@@ -0,0 +1,171 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/aws-efs-csi-driver/pkg/driver/mocks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestVolumeTracker(t *testing.T) {
+	var tracker volumeTracker
+	tracker.add(volumeId, "/target/1")
+	tracker.add(volumeId, "/target/2")
+	tracker.add(volumeId, "/target/2")
+	if value := testutil.ToFloat64(publishedVolumes); value != 1 {
+		t.Fatalf("Expected 1 published volume, got %v", value)
+	}
+
+	if tracker.remove(volumeId, "/target/3") {
+		t.Fatalf("Expected unknown target not to be the last one")
+	}
+	if tracker.remove("fs-def456", "/target/1") {
+		t.Fatalf("Expected unknown volume not to be the last one")
+	}
+	if tracker.remove(volumeId, "/target/1") {
+		t.Fatalf("Expected volume to still be published at /target/2")
+	}
+	if !tracker.remove(volumeId, "/target/2") {
+		t.Fatalf("Expected /target/2 to be the last target of the volume")
+	}
+	if tracker.remove(volumeId, "/target/2") {
+		t.Fatalf("Expected removed target not to be the last one again")
+	}
+	if value := testutil.ToFloat64(publishedVolumes); value != 0 {
+		t.Fatalf("Expected no published volume, got %v", value)
+	}
+}
+
+func TestVolumeTrackerRestore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "volumes.json")
+	tracker := volumeTracker{file: file}
+	tracker.add(volumeId, "/target/1")
+	tracker.add(volumeId, "/target/2")
+	tracker.add("fs-def456", "/target/3")
+	tracker.add("fs-def456", "/target/4")
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockMounter := mocks.NewMockMounter(mockCtrl)
+	mockMounter.EXPECT().GetDeviceName("/target/1").Return(volumeId+":/", 2, nil)
+	mockMounter.EXPECT().GetDeviceName("/target/2").Return(volumeId+":/", 2, nil)
+	// The node rebooted, or the target was unmounted while the node plugin was down.
+	mockMounter.EXPECT().GetDeviceName("/target/3").Return("", 0, nil)
+	mockMounter.EXPECT().GetDeviceName("/target/4").Return("", 0, fmt.Errorf("failed to read mount table"))
+
+	restarted := volumeTracker{file: file}
+	if err := restarted.load(mockMounter); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]map[string]bool{
+		volumeId:    {"/target/1": true, "/target/2": true},
+		"fs-def456": {"/target/4": true},
+	}
+	if !reflect.DeepEqual(restarted.targets, expected) {
+		t.Fatalf("Expected restored volumes %v, got %v", expected, restarted.targets)
+	}
+
+	if restarted.remove(volumeId, "/target/1") || !restarted.remove(volumeId, "/target/2") {
+		t.Fatalf("Expected the restored targets to be tracked")
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"volumes":{"fs-def456":["/target/4"]}}`; string(data) != expected {
+		t.Fatalf("Expected state %s, got %s", expected, data)
+	}
+}
+
+func TestVolumeTrackerLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	missing := volumeTracker{file: filepath.Join(dir, "missing.json")}
+	if err := missing.load(nil); err != nil {
+		t.Fatalf("Expected missing state to be ignored, got %v", err)
+	}
+
+	file := filepath.Join(dir, "volumes.json")
+	if err := os.WriteFile(file, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := volumeTracker{file: file}
+	if err := corrupt.load(nil); err == nil {
+		t.Fatalf("Expected corrupt state to fail to load")
+	}
+}
+
+func TestVolumeTrackerRebuild(t *testing.T) {
+	dir := t.TempDir()
+	kubeletDir := filepath.Join(dir, "kubelet")
+	volumeData := map[string]string{
+		"pod-1/volumes/kubernetes.io~csi/pv-1": `{"driverName":"efs.csi.aws.com","volumeHandle":"fs-abc123"}`,
+		"pod-2/volumes/kubernetes.io~csi/pv-1": `{"driverName":"efs.csi.aws.com","volumeHandle":"fs-abc123"}`,
+		"pod-2/volumes/kubernetes.io~csi/pv-2": `{"driverName":"efs.csi.aws.com","volumeHandle":"fs-def456::fsap-abcd1234"}`,
+		"pod-3/volumes/kubernetes.io~csi/pv-3": `{"driverName":"nfs.csi.k8s.io","volumeHandle":"server#share"}`,
+	}
+	mountInfo := ""
+	for i, volume := range []string{"pod-1/volumes/kubernetes.io~csi/pv-1", "pod-2/volumes/kubernetes.io~csi/pv-1", "pod-2/volumes/kubernetes.io~csi/pv-2", "pod-3/volumes/kubernetes.io~csi/pv-3"} {
+		volumeDir := filepath.Join(kubeletDir, "pods", volume)
+		if err := os.MkdirAll(volumeDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		createFile(t, volumeDir, "vol_data.json", volumeData[volume])
+		mountInfo += fmt.Sprintf("%d 25 0:5%d / %s/mount rw,relatime shared:1 - nfs4 127.0.0.1:/ rw,vers=4.1\n", 40+i, i, volumeDir)
+	}
+	// The staging path of a volume, and a volume of another type, are not published volumes.
+	mountInfo += "50 25 0:60 / " + kubeletDir + "/plugins/kubernetes.io/csi/efs.csi.aws.com/abc/globalmount rw - nfs4 127.0.0.1:/ rw\n"
+	mountInfo += "51 25 0:61 / " + kubeletDir + "/pods/pod-1/volumes/kubernetes.io~empty-dir/cache rw - tmpfs tmpfs rw\n"
+	createFile(t, dir, "mountinfo", mountInfo)
+
+	file := filepath.Join(dir, "volumes.json")
+	tracker := volumeTracker{file: file, mountInfoPath: filepath.Join(dir, "mountinfo"), kubeletDir: kubeletDir}
+	if err := tracker.load(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	podsDir := filepath.Join(kubeletDir, "pods")
+	expected := map[string]map[string]bool{
+		"fs-abc123": {
+			podsDir + "/pod-1/volumes/kubernetes.io~csi/pv-1/mount": true,
+			podsDir + "/pod-2/volumes/kubernetes.io~csi/pv-1/mount": true,
+		},
+		"fs-def456::fsap-abcd1234": {podsDir + "/pod-2/volumes/kubernetes.io~csi/pv-2/mount": true},
+	}
+	if !reflect.DeepEqual(tracker.targets, expected) {
+		t.Fatalf("Expected rebuilt volumes %v, got %v", expected, tracker.targets)
+	}
+	// The rebuilt volumes are persisted, and restored from the file from then on.
+	if _, err := os.Stat(file); err != nil {
+		t.Fatalf("Expected rebuilt volumes to be persisted: %v", err)
+	}
+
+	unset := volumeTracker{mountInfoPath: filepath.Join(dir, "mountinfo"), kubeletDir: kubeletDir}
+	if err := unset.load(nil); err != nil || !reflect.DeepEqual(unset.targets, expected) {
+		t.Fatalf("Expected volumes %v to be rebuilt without state file, got %v, %v", expected, unset.targets, err)
+	}
+
+	broken := volumeTracker{mountInfoPath: filepath.Join(dir, "missing"), kubeletDir: kubeletDir}
+	if err := broken.load(nil); err == nil {
+		t.Fatalf("Expected rebuild to fail without mountinfo")
+	}
+}